@@ -0,0 +1,401 @@
+// Package assetcache caches large binary assets such as image
+// thumbnails. It composes the features of the LRU cache: entries are
+// weighed by their size in bytes, stored gzip compressed when that
+// saves space, spilled to disk when evicted from memory and served
+// over HTTP.
+package assetcache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	cache "github.com/MeteorsLiu/LRUCache"
+)
+
+// Asset is a cached asset.
+type Asset struct {
+	Name        string
+	ContentType string
+	ModTime     time.Time
+	// Data is the uncompressed content.
+	Data []byte
+}
+
+// Options configure a Cache.
+type Options struct {
+	// MaxBytes bounds the memory held by the stored assets.
+	MaxBytes int64
+	// Compress stores assets gzip compressed when it makes them smaller.
+	Compress bool
+	// Dir, if set, is where assets evicted from memory are written,
+	// to be loaded back on their next lookup. Assets larger than
+	// MaxBytes are only kept there.
+	Dir string
+	// MaxDiskBytes bounds the size of the assets in Dir, the least
+	// recently used ones are removed first. Zero means 10 times
+	// MaxBytes, or no bound without a MaxBytes.
+	MaxDiskBytes int64
+}
+
+// Cache is a cache of assets.
+type Cache struct {
+	mem      *cache.Cache
+	compress bool
+	maxBytes int64
+	dir      string
+	maxDisk  int64
+	// disk indexes the assets in dir by name, weighed by size.
+	disk *cache.Cache
+
+	// pending holds the assets evicted from memory until the spill
+	// goroutine wrote them to disk, pendingBytes is their size.
+	mu           sync.Mutex
+	pending      map[string]*stored
+	pendingBytes int64
+	wake         chan struct{}
+	done         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// stored is an asset as kept in memory and on disk.
+type stored struct {
+	Name        string
+	ContentType string
+	ModTime     time.Time
+	Gzipped     bool
+	Data        []byte
+}
+
+// New returns an asset cache. If opts.Dir is set, assets spilled there
+// by a previous Cache are picked up, and Close must be called to stop
+// the goroutine writing the evicted assets.
+func New(opts Options) (*Cache, error) {
+	a := &Cache{compress: opts.Compress, maxBytes: opts.MaxBytes, dir: opts.Dir}
+	a.mem = cache.New(0,
+		cache.WithMaxCost(opts.MaxBytes),
+		cache.WithCost(func(_ cache.Key, v interface{}) int64 {
+			return int64(len(v.(*stored).Data))
+		}))
+	if a.dir == "" {
+		return a, nil
+	}
+	if err := os.MkdirAll(a.dir, 0o755); err != nil {
+		return nil, err
+	}
+	a.maxDisk = opts.MaxDiskBytes
+	if a.maxDisk <= 0 {
+		a.maxDisk = 10 * opts.MaxBytes
+	}
+	a.disk = cache.Unbounded(
+		cache.WithMaxCost(a.maxDisk),
+		cache.WithCost(func(_ cache.Key, v interface{}) int64 {
+			return v.(int64)
+		}))
+	a.disk.OnEvicted = func(key cache.Key, _ interface{}) {
+		os.Remove(key.(string))
+	}
+	if err := a.indexDir(); err != nil {
+		return nil, err
+	}
+	a.pending = make(map[string]*stored)
+	a.wake = make(chan struct{}, 1)
+	a.done = make(chan struct{})
+	a.mem.OnEvicted = a.evicted
+	a.wg.Add(1)
+	go a.spillLoop()
+	return a, nil
+}
+
+// indexDir adds the assets spilled by a previous Cache to the disk
+// index, so they count towards MaxDiskBytes.
+func (a *Cache) indexDir() error {
+	files, err := ioutil.ReadDir(a.dir)
+	if err != nil {
+		return err
+	}
+	for _, fi := range files {
+		if len(fi.Name()) == 2*sha256.Size && fi.Mode().IsRegular() {
+			if _, err := hex.DecodeString(fi.Name()); err == nil {
+				a.disk.Set(filepath.Join(a.dir, fi.Name()), fi.Size())
+			}
+		}
+	}
+	return nil
+}
+
+// Close stops the goroutine spilling assets to disk, after it wrote
+// the pending ones.
+func (a *Cache) Close() {
+	if a.done == nil {
+		return
+	}
+	a.mu.Lock()
+	select {
+	case <-a.done:
+	default:
+		close(a.done)
+	}
+	a.mu.Unlock()
+	a.wg.Wait()
+}
+
+// oversize reports whether s can't fit in memory.
+func (a *Cache) oversize(s *stored) bool {
+	return a.maxBytes > 0 && int64(len(s.Data)) > a.maxBytes
+}
+
+// Put adds an asset to the cache.
+func (a *Cache) Put(asset *Asset) error {
+	s := &stored{
+		Name:        asset.Name,
+		ContentType: asset.ContentType,
+		ModTime:     asset.ModTime,
+		Data:        asset.Data,
+	}
+	if a.compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(asset.Data); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		if buf.Len() < len(asset.Data) {
+			s.Data, s.Gzipped = buf.Bytes(), true
+		}
+	}
+	if a.oversize(s) {
+		// It would only be evicted right away.
+		if a.dir != "" {
+			a.queue(s)
+		}
+		return nil
+	}
+	a.mem.Set(asset.Name, s)
+	return nil
+}
+
+// Get returns the asset name, loading it back from disk if it had been
+// evicted from memory.
+func (a *Cache) Get(name string) (*Asset, bool) {
+	s, ok := a.lookup(name)
+	if !ok {
+		return nil, false
+	}
+	data := s.Data
+	if s.Gzipped {
+		zr, err := gzip.NewReader(bytes.NewReader(s.Data))
+		if err != nil {
+			return nil, false
+		}
+		if data, err = ioutil.ReadAll(zr); err != nil {
+			return nil, false
+		}
+	}
+	return &Asset{Name: s.Name, ContentType: s.ContentType, ModTime: s.ModTime, Data: data}, true
+}
+
+func (a *Cache) lookup(name string) (*stored, bool) {
+	if v, ok := a.mem.Get(name); ok {
+		return v.(*stored), true
+	}
+	if a.dir == "" {
+		return nil, false
+	}
+	a.mu.Lock()
+	s, ok := a.pending[name]
+	if ok && !a.oversize(s) {
+		delete(a.pending, name)
+		a.pendingBytes -= int64(len(s.Data))
+	}
+	a.mu.Unlock()
+	if !ok {
+		if s, ok = a.load(name); !ok {
+			return nil, false
+		}
+	}
+	if !a.oversize(s) {
+		a.mem.Set(name, s)
+	}
+	return s, true
+}
+
+// load reads the asset name from disk. Assets which fit in memory are
+// removed from disk, as they're readmitted.
+func (a *Cache) load(name string) (*stored, bool) {
+	path := a.path(name)
+	if _, ok := a.disk.Get(path); !ok {
+		return nil, false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		a.disk.Remove(path)
+		return nil, false
+	}
+	var s stored
+	err = gob.NewDecoder(f).Decode(&s)
+	f.Close()
+	if err != nil || s.Name != name {
+		return nil, false
+	}
+	if !a.oversize(&s) {
+		a.disk.Remove(path)
+	}
+	return &s, true
+}
+
+// evicted queues an asset evicted from memory to be spilled. It runs
+// with the memory cache locked, so it leaves the I/O to spillLoop.
+func (a *Cache) evicted(_ cache.Key, v interface{}) {
+	a.queue(v.(*stored))
+}
+
+// queue queues s to be spilled. Assets are dropped rather than queued
+// past MaxDiskBytes of pending assets, if the disk can't keep up.
+func (a *Cache) queue(s *stored) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	select {
+	case <-a.done:
+		return
+	default:
+	}
+	n := int64(len(s.Data))
+	if old, ok := a.pending[s.Name]; ok {
+		a.pendingBytes -= int64(len(old.Data))
+	} else if a.maxDisk > 0 && a.pendingBytes+n > a.maxDisk {
+		return
+	}
+	a.pending[s.Name] = s
+	a.pendingBytes += n
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+// spillLoop writes the pending assets to disk until Close is called.
+func (a *Cache) spillLoop() {
+	defer a.wg.Done()
+	for {
+		select {
+		case <-a.wake:
+			a.spillPending()
+		case <-a.done:
+			a.spillPending()
+			return
+		}
+	}
+}
+
+func (a *Cache) spillPending() {
+	for {
+		a.mu.Lock()
+		var s *stored
+		for _, s = range a.pending {
+			break
+		}
+		a.mu.Unlock()
+		if s == nil {
+			return
+		}
+		a.spill(s)
+		a.mu.Lock()
+		// It's still pending unless a lookup took it back meanwhile.
+		if a.pending[s.Name] == s {
+			delete(a.pending, s.Name)
+			a.pendingBytes -= int64(len(s.Data))
+		}
+		a.mu.Unlock()
+	}
+}
+
+// spill writes s to disk. An asset which can't be written is simply
+// dropped.
+func (a *Cache) spill(s *stored) {
+	f, err := ioutil.TempFile(a.dir, ".spill-")
+	if err != nil {
+		return
+	}
+	err = gob.NewEncoder(f).Encode(s)
+	var size int64
+	if fi, serr := f.Stat(); serr == nil {
+		size = fi.Size()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	path := a.path(s.Name)
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return
+	}
+	a.disk.Set(path, size)
+}
+
+func (a *Cache) path(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return filepath.Join(a.dir, hex.EncodeToString(sum[:]))
+}
+
+// Handler serves assets named by the request path. Assets which aren't
+// cached are produced by load, e.g. by rendering a thumbnail, and
+// cached. Clients accepting gzip get compressed assets as they are.
+func (a *Cache) Handler(load func(name string) (*Asset, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Path
+		s, ok := a.lookup(name)
+		if !ok {
+			asset, err := load(name)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			if err := a.Put(asset); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if s, ok = a.lookup(name); !ok {
+				// Too big to be cached at all.
+				serve(w, r, asset.ContentType, asset.ModTime, asset.Data, false)
+				return
+			}
+		}
+		if s.Gzipped && !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			asset, ok := a.Get(name)
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			serve(w, r, asset.ContentType, asset.ModTime, asset.Data, false)
+			return
+		}
+		serve(w, r, s.ContentType, s.ModTime, s.Data, s.Gzipped)
+	})
+}
+
+func serve(w http.ResponseWriter, r *http.Request, contentType string, modTime time.Time, data []byte, gzipped bool) {
+	h := w.Header()
+	if contentType != "" {
+		h.Set("Content-Type", contentType)
+	}
+	h.Add("Vary", "Accept-Encoding")
+	if gzipped {
+		h.Set("Content-Encoding", "gzip")
+	}
+	http.ServeContent(w, r, "", modTime, bytes.NewReader(data))
+}
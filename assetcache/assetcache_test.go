@@ -0,0 +1,136 @@
+package assetcache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSpillToDisk(t *testing.T) {
+	a, err := New(Options{MaxBytes: 1500, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	for _, name := range []string{"a", "b", "c"} {
+		a.Put(&Asset{Name: name, Data: bytes.Repeat([]byte(name), 1000)})
+	}
+	// Only one asset fits in memory, the others were spilled.
+	for _, name := range []string{"a", "b", "c"} {
+		asset, ok := a.Get(name)
+		if !ok || len(asset.Data) != 1000 || asset.Data[0] != name[0] {
+			t.Fatalf("Get(%s) = %v", name, ok)
+		}
+	}
+	if _, ok := a.Get("missing"); ok {
+		t.Fatal("Get of a missing asset succeeded")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	a, _ := New(Options{MaxBytes: 1 << 20, Compress: true})
+	loads := 0
+	h := a.Handler(func(name string) (*Asset, error) {
+		loads++
+		if name != "/thumb.txt" {
+			return nil, errors.New("no such asset")
+		}
+		return &Asset{Name: name, ContentType: "text/plain", Data: []byte(strings.Repeat("thumbnail ", 100))}, nil
+	})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/thumb.txt", nil))
+	if w.Code != 200 || w.Body.Len() != 1000 || w.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("plain response = %d, %d bytes, %v", w.Code, w.Body.Len(), w.Header())
+	}
+
+	r := httptest.NewRequest("GET", "/thumb.txt", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("gzip response headers = %v", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadAll(zr); len(data) != 1000 {
+		t.Fatalf("gunzipped %d bytes, want 1000", len(data))
+	}
+	if loads != 1 {
+		t.Fatalf("loaded %d times, want 1", loads)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	if w.Code != 404 {
+		t.Fatalf("missing asset status = %d", w.Code)
+	}
+}
+
+func TestOversizeAsset(t *testing.T) {
+	a, err := New(Options{MaxBytes: 100, MaxDiskBytes: 1 << 20, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	loads := 0
+	h := a.Handler(func(name string) (*Asset, error) {
+		loads++
+		return &Asset{Name: name, Data: bytes.Repeat([]byte("x"), 1000)}, nil
+	})
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/big", nil))
+		if w.Body.Len() != 1000 {
+			t.Fatalf("served %d bytes, want 1000", w.Body.Len())
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("loaded %d times, want 1", loads)
+	}
+	if n := a.mem.Stats().Evictions; n != 0 {
+		t.Fatalf("the oversize asset caused %d evictions", n)
+	}
+}
+
+func TestMaxDiskBytes(t *testing.T) {
+	dir := t.TempDir()
+	a, err := New(Options{MaxBytes: 1000, MaxDiskBytes: 2500, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		a.Put(&Asset{Name: name, Data: bytes.Repeat([]byte(name), 1000)})
+	}
+	a.Close()
+	files, _ := ioutil.ReadDir(dir)
+	var size int64
+	for _, fi := range files {
+		size += fi.Size()
+	}
+	if size > 2500 || len(files) == 0 {
+		t.Fatalf("%d spilled files of %d bytes, want at most 2500 bytes", len(files), size)
+	}
+
+	// A new cache picks the spilled assets up.
+	a, err = New(Options{MaxBytes: 1000, MaxDiskBytes: 2500, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	found := 0
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if _, ok := a.Get(name); ok {
+			found++
+		}
+	}
+	if found != len(files) {
+		t.Fatalf("picked up %d spilled assets, want %d", found, len(files))
+	}
+}
@@ -0,0 +1,88 @@
+package cache
+
+import "context"
+
+// Op is a kind of cache mutation reported to the audit hook.
+type Op int
+
+const (
+	// OpSet adds or updates a key.
+	OpSet Op = iota
+	// OpRemove removes a key.
+	OpRemove
+	// OpClear removes every key, it's reported with a nil key.
+	OpClear
+)
+
+func (op Op) String() string {
+	switch op {
+	case OpSet:
+		return "set"
+	case OpRemove:
+		return "remove"
+	case OpClear:
+		return "clear"
+	}
+	return "unknown"
+}
+
+// WithAuditHook calls hook for every mutation of the cache by its
+// callers, so caches of sensitive data can produce audit trails.
+// actor is the one carried by the context of SetContext, RemoveContext
+// or SetWait, see WithActor, and empty for the methods without one.
+// Evictions and expirations aren't reported, see OnEvicted. hook is
+// called with the cache locked, like OnEvicted, and must not use it.
+func WithAuditHook(hook func(op Op, key Key, actor string)) Option {
+	return func(c *Cache) {
+		c.auditHook = hook
+	}
+}
+
+// actorKey is the key of the actor in a context.
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying actor, e.g. the user on
+// whose behalf a request handler writes to the cache.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// actorFrom returns the actor carried by ctx.
+func actorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// audit reports a mutation to the audit hook. The caller must hold c.mu.
+func (c *Cache) audit(op Op, key Key) {
+	if c.auditHook != nil {
+		c.auditHook(op, key, c.actor)
+	}
+}
+
+// SetContext is like Set, reporting the actor carried by ctx to the
+// audit hook, see WithAuditHook.
+func (c *Cache) SetContext(ctx context.Context, key Key, value interface{}) {
+	if !c.allowWrite() {
+		return
+	}
+	expire := c.expireAt(c.defaultTTL(value))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.actor = actorFrom(ctx)
+	defer func() { c.actor = "" }()
+	c.add(key, value, expire)
+}
+
+// RemoveContext is like Remove, reporting the actor carried by ctx to
+// the audit hook, see WithAuditHook.
+func (c *Cache) RemoveContext(ctx context.Context, key Key) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil {
+		return
+	}
+	c.actor = actorFrom(ctx)
+	defer func() { c.actor = "" }()
+	c.remove(key)
+}
@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestAuditHook(t *testing.T) {
+	var trail []string
+	c := New(10, WithAuditHook(func(op Op, key Key, actor string) {
+		trail = append(trail, fmt.Sprintf("%v %v %s", op, key, actor))
+	}))
+	ctx := WithActor(context.Background(), "alice")
+	c.SetContext(ctx, "a", 1)
+	c.Set("b", 2)
+	c.AppendTo("l", 1)
+	c.AppendTo("l", 2)
+	c.RemoveContext(ctx, "a")
+	c.Remove("missing")
+	c.SetWait(WithActor(ctx, "bob"), "c", 3)
+	c.Clear()
+	want := []string{
+		"set a alice",
+		"set b ",
+		"set l ",
+		"set l ",
+		"remove a alice",
+		"set c bob",
+		"clear <nil> ",
+	}
+	if !reflect.DeepEqual(trail, want) {
+		t.Fatalf("audit trail = %q, want %q", trail, want)
+	}
+}
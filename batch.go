@@ -0,0 +1,32 @@
+package cache
+
+import "time"
+
+// Expiring is a value stored with its own TTL, see SetAllWithExpire.
+type Expiring struct {
+	Value interface{}
+	TTL   time.Duration
+}
+
+// SetAll adds all entries to the cache under a single lock, for bulk
+// hydration e.g. from a database query. The cache evicts at most once,
+// after all entries have been inserted.
+func (c *Cache) SetAll(entries map[Key]interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, v := range entries {
+		c.insert(k, v, c.expireAt(c.defaultTTL(v)))
+	}
+	c.evict()
+}
+
+// SetAllWithExpire is like SetAll, with a TTL per entry.
+// The TTLs are clamped like in SetWithExpire.
+func (c *Cache) SetAllWithExpire(entries map[Key]Expiring) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, v := range entries {
+		c.insert(k, v.Value, c.expireAt(v.TTL))
+	}
+	c.evict()
+}
@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetAll(t *testing.T) {
+	ce := New(100)
+	ce.SetAll(map[Key]interface{}{"a": 1, "b": 2})
+	ce.SetAllWithExpire(map[Key]Expiring{
+		"c": {Value: 3, TTL: time.Hour},
+		"d": {Value: 4},
+	})
+	for k, want := range map[Key]interface{}{"a": 1, "b": 2, "c": 3, "d": 4} {
+		if v, ok := ce.Get(k); !ok || v != want {
+			t.Errorf("Get(%v) = %v, %v, want %v", k, v, ok, want)
+		}
+	}
+
+	evicted := 0
+	small := New(2)
+	small.OnEvicted = func(Key, interface{}) { evicted++ }
+	entries := make(map[Key]interface{})
+	for i := 0; i < 10; i++ {
+		entries[i] = i
+	}
+	small.SetAll(entries)
+	if n := small.Len(); n == 10 || evicted != 10-n {
+		t.Errorf("Len = %d after %d evictions", n, evicted)
+	}
+}
@@ -0,0 +1,147 @@
+package bench
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"testing"
+
+	cache "github.com/MeteorsLiu/LRUCache"
+	"github.com/dgraph-io/ristretto"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	// capacity is the number of entries of every cache.
+	capacity = 1 << 12
+	// traceLen is the length of the traces, a power of 2.
+	traceLen = 1 << 16
+)
+
+// cacheImpl is the part of a cache the benchmarks use.
+type cacheImpl interface {
+	Get(key uint64) bool
+	Set(key uint64, value interface{})
+}
+
+type lruCache struct{ c *cache.Cache }
+
+func (c lruCache) Get(key uint64) bool {
+	_, ok := c.c.Get(key)
+	return ok
+}
+
+func (c lruCache) Set(key uint64, value interface{}) { c.c.Set(key, value) }
+
+type golangLRU struct{ c *lru.Cache }
+
+func (c golangLRU) Get(key uint64) bool {
+	_, ok := c.c.Get(key)
+	return ok
+}
+
+func (c golangLRU) Set(key uint64, value interface{}) { c.c.Add(key, value) }
+
+type ristrettoCache struct{ c *ristretto.Cache }
+
+func (c ristrettoCache) Get(key uint64) bool {
+	_, ok := c.c.Get(key)
+	return ok
+}
+
+func (c ristrettoCache) Set(key uint64, value interface{}) { c.c.Set(key, value, 1) }
+
+var impls = []struct {
+	name string
+	new  func() cacheImpl
+}{
+	{"LRUCache", func() cacheImpl { return lruCache{cache.New(capacity)} }},
+	{"LRUCacheRobin", func() cacheImpl {
+		return lruCache{cache.New(capacity, cache.WithStore(cache.NewRobinStore))}
+	}},
+	{"GolangLRU", func() cacheImpl {
+		c, err := lru.New(capacity)
+		if err != nil {
+			panic(err)
+		}
+		return golangLRU{c}
+	}},
+	{"Ristretto", func() cacheImpl {
+		c, err := ristretto.NewCache(&ristretto.Config{
+			NumCounters: capacity * 10,
+			MaxCost:     capacity,
+			BufferItems: 64,
+			// Every entry costs 1, as in the other caches.
+			IgnoreInternalCost: true,
+		})
+		if err != nil {
+			panic(err)
+		}
+		return ristrettoCache{c}
+	}},
+}
+
+// zipfTrace returns a trace of keys following a Zipf distribution of
+// exponent s, the same for a given seed.
+func zipfTrace(seed int64, s float64) []uint64 {
+	z := rand.NewZipf(rand.New(rand.NewSource(seed)), s, 1, 1<<20)
+	trace := make([]uint64, traceLen)
+	for i := range trace {
+		trace[i] = z.Uint64()
+	}
+	return trace
+}
+
+// BenchmarkZipf replays Zipf traces on every cache, reporting the hit
+// ratio along with the throughput and the allocations.
+func BenchmarkZipf(b *testing.B) {
+	for _, s := range []struct {
+		name string
+		s    float64
+	}{{"S1.01", 1.01}, {"S1.2", 1.2}} {
+		trace := zipfTrace(1, s.s)
+		for _, impl := range impls {
+			b.Run(s.name+"/"+impl.name, func(b *testing.B) {
+				c := impl.new()
+				var hits int
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					key := trace[i&(traceLen-1)]
+					if c.Get(key) {
+						hits++
+					} else {
+						c.Set(key, i)
+					}
+				}
+				b.ReportMetric(float64(hits)/float64(b.N), "hits/op")
+			})
+		}
+	}
+}
+
+// BenchmarkZipfParallel is BenchmarkZipf with GOMAXPROCS goroutines
+// sharing each cache, every one replaying its own trace.
+func BenchmarkZipfParallel(b *testing.B) {
+	for _, impl := range impls {
+		b.Run(impl.name, func(b *testing.B) {
+			c := impl.new()
+			var seed, hits int64
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				trace := zipfTrace(atomic.AddInt64(&seed, 1), 1.01)
+				var n int64
+				for i := 0; pb.Next(); i++ {
+					key := trace[i&(traceLen-1)]
+					if c.Get(key) {
+						n++
+					} else {
+						c.Set(key, i)
+					}
+				}
+				atomic.AddInt64(&hits, n)
+			})
+			b.ReportMetric(float64(hits)/float64(b.N), "hits/op")
+		})
+	}
+}
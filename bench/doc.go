@@ -0,0 +1,6 @@
+// Package bench compares the cache against hashicorp/golang-lru and
+// ristretto on Zipf traces. It's a module of its own, so their
+// dependencies stay out of the cache module. Run the comparison with
+//
+//	cd bench && go test -bench .
+package bench
@@ -0,0 +1,19 @@
+module github.com/MeteorsLiu/LRUCache/bench
+
+go 1.17
+
+replace github.com/MeteorsLiu/LRUCache => ../
+
+require (
+	github.com/MeteorsLiu/LRUCache v0.0.0-00010101000000-000000000000
+	github.com/dgraph-io/ristretto v0.1.1
+	github.com/hashicorp/golang-lru v0.5.4
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.1.2 // indirect
+	github.com/dustin/go-humanize v1.0.0 // indirect
+	github.com/golang/glog v1.0.0 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	golang.org/x/sys v0.7.0 // indirect
+)
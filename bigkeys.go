@@ -0,0 +1,154 @@
+package cache
+
+import "container/heap"
+
+// KeyCost is a key and the cost of its entry, see LargestKeys.
+type KeyCost struct {
+	Key  Key
+	Cost int64
+}
+
+// LargestKeys returns the n unexpired entries with the highest cost,
+// see WithCost, most costly first, so operators can find the giant
+// values dominating the memory of the cache. WithCost indexes the
+// entries by cost as they're written, so it takes O(n log n) time plus
+// the expired entries it skips, whatever the size of the cache. Without
+// WithCost every entry costs 1 and the n most recently used are
+// returned.
+func (c *Cache) LargestKeys(n int) []KeyCost {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil || n <= 0 {
+		return nil
+	}
+	now := c.now()
+	if c.byCost != nil {
+		return c.byCost.largest(n, now)
+	}
+	var top []KeyCost
+	for ele := c.ll.Front(); ele != nil && len(top) < n; ele = ele.Next() {
+		if e := ele.Value.(*entry); !e.expired(now) {
+			top = append(top, KeyCost{e.key, e.cost})
+		}
+	}
+	return top
+}
+
+// costIndex is a max-heap of the entries by cost, see LargestKeys.
+// Every entry holds its position in costIdx. The methods of a nil
+// index do nothing.
+type costIndex struct {
+	entries []*entry
+}
+
+func (x *costIndex) Len() int           { return len(x.entries) }
+func (x *costIndex) Less(i, j int) bool { return x.entries[i].cost > x.entries[j].cost }
+func (x *costIndex) Swap(i, j int) {
+	x.entries[i], x.entries[j] = x.entries[j], x.entries[i]
+	x.entries[i].costIdx = i
+	x.entries[j].costIdx = j
+}
+
+func (x *costIndex) Push(v interface{}) {
+	e := v.(*entry)
+	e.costIdx = len(x.entries)
+	x.entries = append(x.entries, e)
+}
+
+func (x *costIndex) Pop() interface{} {
+	last := len(x.entries) - 1
+	e := x.entries[last]
+	x.entries[last] = nil
+	x.entries = x.entries[:last]
+	return e
+}
+
+// add indexes e.
+func (x *costIndex) add(e *entry) {
+	if x != nil {
+		heap.Push(x, e)
+	}
+}
+
+// remove drops e from the index.
+func (x *costIndex) remove(e *entry) {
+	if x != nil {
+		heap.Remove(x, e.costIdx)
+	}
+}
+
+// fix moves e after its cost changed.
+func (x *costIndex) fix(e *entry) {
+	if x != nil {
+		heap.Fix(x, e.costIdx)
+	}
+}
+
+// reset drops every entry.
+func (x *costIndex) reset() {
+	if x != nil {
+		x.entries = nil
+	}
+}
+
+// largest returns the n unexpired entries with the highest cost, most
+// costly first. It walks the heap best first from the root, so it only
+// visits the entries it returns or skips and their children.
+func (x *costIndex) largest(n int, now int64) []KeyCost {
+	var top []KeyCost
+	next := &frontier{x: x}
+	if len(x.entries) > 0 {
+		heap.Push(next, 0)
+	}
+	for next.Len() > 0 && len(top) < n {
+		i := heap.Pop(next).(int)
+		if e := x.entries[i]; !e.expired(now) {
+			top = append(top, KeyCost{e.key, e.cost})
+		}
+		for _, child := range [...]int{2*i + 1, 2*i + 2} {
+			if child < len(x.entries) {
+				heap.Push(next, child)
+			}
+		}
+	}
+	return top
+}
+
+// frontier is a max-heap of positions in a costIndex by cost.
+type frontier struct {
+	x   *costIndex
+	pos []int
+}
+
+func (f *frontier) Len() int           { return len(f.pos) }
+func (f *frontier) Less(i, j int) bool { return f.x.Less(f.pos[i], f.pos[j]) }
+func (f *frontier) Swap(i, j int)      { f.pos[i], f.pos[j] = f.pos[j], f.pos[i] }
+func (f *frontier) Push(v interface{}) { f.pos = append(f.pos, v.(int)) }
+func (f *frontier) Pop() interface{} {
+	i := f.pos[len(f.pos)-1]
+	f.pos = f.pos[:len(f.pos)-1]
+	return i
+}
+
+// largeEntries is set by WithLargeEntryHook.
+type largeEntries struct {
+	threshold int64
+	fn        func(key Key, cost int64)
+}
+
+// WithLargeEntryHook calls fn whenever a value costing at least
+// threshold is written, e.g. to log a warning about it. fn is called
+// with the cache locked, like OnEvicted, and must not use it.
+func WithLargeEntryHook(threshold int64, fn func(key Key, cost int64)) Option {
+	return func(c *Cache) {
+		c.large = &largeEntries{threshold: threshold, fn: fn}
+	}
+}
+
+// checkLarge reports the write of a large value to the hook.
+// The caller must hold c.mu.
+func (c *Cache) checkLarge(key Key, cost int64) {
+	if c.large != nil && cost >= c.large.threshold {
+		c.large.fn(key, cost)
+	}
+}
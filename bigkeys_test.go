@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLargestKeys(t *testing.T) {
+	var large []Key
+	c := New(0,
+		WithCost(func(_ Key, v interface{}) int64 { return int64(len(v.(string))) }),
+		WithLargeEntryHook(5, func(key Key, _ int64) { large = append(large, key) }),
+	)
+	c.Set("a", "x")
+	c.Set("b", "xxxxxx")
+	c.Set("c", "xxx")
+	c.Set("d", "xxxxxxxxx")
+	c.Set("e", "xx")
+
+	want := []KeyCost{{"d", 9}, {"b", 6}, {"c", 3}}
+	if got := c.LargestKeys(3); !reflect.DeepEqual(got, want) {
+		t.Fatalf("LargestKeys(3) = %v, want %v", got, want)
+	}
+	if got := c.LargestKeys(10); len(got) != 5 {
+		t.Fatalf("LargestKeys(10) returned %d keys, want 5", len(got))
+	}
+	if !reflect.DeepEqual(large, []Key{"b", "d"}) {
+		t.Fatalf("hook called for %v, want [b d]", large)
+	}
+	c.Set("a", "xxxxx")
+	if len(large) != 3 || large[2] != "a" {
+		t.Fatalf("hook called for %v after a grew", large)
+	}
+}
+
+func TestLargestKeysIndex(t *testing.T) {
+	c := New(4, WithCost(func(_ Key, v interface{}) int64 { return v.(int64) }))
+	for i := int64(1); i <= 6; i++ {
+		c.Set(i, i*10)
+	}
+	// 1 and 2 were evicted.
+	want := []KeyCost{{int64(6), 60}, {int64(5), 50}}
+	if got := c.LargestKeys(2); !reflect.DeepEqual(got, want) {
+		t.Fatalf("LargestKeys(2) = %v, want %v", got, want)
+	}
+	c.Remove(int64(6))
+	c.Set(int64(3), int64(100))
+	if _, err := c.Increment(int64(4), -35, 0); err != nil {
+		t.Fatal(err)
+	}
+	c.SetWithExpire(int64(7), int64(200), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	want = []KeyCost{{int64(3), 100}, {int64(5), 50}, {int64(4), 5}}
+	if got := c.LargestKeys(10); !reflect.DeepEqual(got, want) {
+		t.Fatalf("LargestKeys(10) = %v, want %v", got, want)
+	}
+	c.Clear()
+	if got := c.LargestKeys(10); len(got) != 0 {
+		t.Fatalf("LargestKeys = %v after Clear", got)
+	}
+}
+
+func TestLargestKeysWithoutCost(t *testing.T) {
+	c := New(0)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	want := []KeyCost{{"c", 1}, {"b", 1}}
+	if got := c.LargestKeys(2); !reflect.DeepEqual(got, want) {
+		t.Fatalf("LargestKeys(2) = %v, want %v", got, want)
+	}
+}
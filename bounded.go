@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnbounded is returned by NewBounded when nothing bounds the size
+// of the cache.
+var ErrUnbounded = errors.New("cache: neither MaxEntries nor MaxCost bounds the cache")
+
+// Unbounded creates a Cache without a limit on the number of entries,
+// like New(0, opts...), stating that the caller means it. The entries
+// may still be bounded by cost, see WithMaxCost, or removed by TTLs.
+func Unbounded(opts ...Option) *Cache {
+	return New(0, opts...)
+}
+
+// NewBounded is like New, but returns ErrUnbounded instead of a cache
+// growing without limit when maxEntries is zero and opts don't set a
+// MaxCost, for callers taking maxEntries from configuration where a
+// zero is more likely a mistake than a request for an unbounded cache.
+// A negative maxEntries is an error as well, rather than an unbounded
+// cache.
+func NewBounded(maxEntries int, opts ...Option) (*Cache, error) {
+	if maxEntries < 0 {
+		return nil, fmt.Errorf("cache: negative MaxEntries %d", maxEntries)
+	}
+	c := New(maxEntries, opts...)
+	if c.MaxEntries == 0 && c.MaxCost <= 0 {
+		c.Close()
+		return nil, ErrUnbounded
+	}
+	return c, nil
+}
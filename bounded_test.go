@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewBounded(t *testing.T) {
+	if _, err := NewBounded(0); !errors.Is(err, ErrUnbounded) {
+		t.Fatalf("NewBounded(0) error = %v, want ErrUnbounded", err)
+	}
+	if _, err := NewBounded(-1); err == nil {
+		t.Fatal("NewBounded(-1) succeeded")
+	}
+	c, err := NewBounded(0, WithMaxCost(10))
+	if err != nil || c.MaxCost != 10 {
+		t.Fatalf("NewBounded(0, WithMaxCost(10)) = %v, %v", c, err)
+	}
+	c, err = NewBounded(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		c.Set(i, i)
+	}
+	if n := c.Len(); n != 2 {
+		t.Fatalf("Len() = %d for a bounded cache", n)
+	}
+}
+
+func TestUnbounded(t *testing.T) {
+	c := Unbounded()
+	for i := 0; i < 1000; i++ {
+		c.Set(i, i)
+	}
+	if n := c.Len(); n != 1000 {
+		t.Fatalf("Len() = %d, want 1000", n)
+	}
+}
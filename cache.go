@@ -2,7 +2,9 @@ package cache
 
 import (
 	"container/list"
+	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,18 +23,132 @@ limitations under the License.
 
 // Package lru implements an LRU cache.
 
-// Cache is an LRU cache. It is not safe for concurrent access.
+// Cache is an LRU cache. Its methods are safe for concurrent use by
+// multiple goroutines, they're serialized by a mutex except for the
+// lookups served by WithReadSnapshots and the atomic gauges like
+// ApproxLen and Cost. The exported fields must be set before the cache
+// is shared, use ApplyConfig to change the limits afterwards. The
+// callbacks, like OnEvicted, are called with the cache locked and must
+// not use it, unless their option says otherwise.
 type Cache struct {
+	// size is the number of entries, see ApproxLen, and cost their
+	// total cost. They and the counters are accessed atomically and
+	// come first for 64-bit alignment.
+	size int64
+	cost int64
+	// hits and misses count the lookups, see Stats.
+	hits, misses counter
+	// rejected counts the writes rejected by the write limiter.
+	rejected counter
+	// draining is set by Drain.
+	draining int32
+
 	// MaxEntries is the maximum number of cache entries before
-	// an item is evicted. Zero means no limit.
+	// an item is evicted. Zero or a negative value means no limit.
 	MaxEntries int
 
+	// MaxCost is the maximum total cost of the cache entries before
+	// an item is evicted, see WithCost. Zero means no limit.
+	MaxCost int64
+
 	// OnEvicted optionally specifies a callback function to be
 	// executed when an entry is purged from the cache.
+	// For entries handed out by Acquire, it's deferred until
+	// the last reader releases them.
+	// It's called with the cache locked, in eviction order, before
+	// the call which purged the entry returns; see WithOrderedEvictions
+	// to deliver it asynchronously in the same order.
 	OnEvicted func(key Key, value interface{})
+	// onEvictedCtx is set by WithOnEvictedContext, evictCtx is the
+	// context of the purge in progress.
+	onEvictedCtx func(ctx context.Context, key Key, value interface{})
+	evictCtx     context.Context
+	// dispatcher is set by WithOrderedEvictions.
+	dispatcher *workerPool
+	// large is set by WithLargeEntryHook.
+	large *largeEntries
+	// cardinality is set by WithKeyCardinality.
+	cardinality *hyperLogLog
+	// prefixes is set by WithPrefixStats and WithPrefixes.
+	prefixes *prefixStats
+	// rolling is set by WithRollingStats.
+	rolling *rollingStats
 
 	ll    *list.List
-	cache map[interface{}]*list.Element
+	store Store
+	// newStore creates the store, see WithStore.
+	newStore func() Store
+
+	// ttls holds the ttlBounds.
+	ttls atomic.Value
+	// ttlFromValue derives the TTL of entries added without one.
+	ttlFromValue func(value interface{}) time.Duration
+	// costOf returns the cost of an entry, see WithCost.
+	costOf func(key Key, value interface{}) int64
+	// byCost indexes the entries by cost under WithCost, see LargestKeys.
+	byCost *costIndex
+	// maxAge is set by WithMaxEntryAge, maxHits by WithMaxHits and
+	// maxIdle by WithMaxIdle.
+	maxAge  int64
+	maxHits uint64
+	maxIdle int64
+	// valueEqual is set by WithValueEqual.
+	valueEqual func(a, b interface{}) bool
+	typeGuard  *typeGuard
+	// provenance is set by WithProvenance.
+	provenance bool
+	// auditHook is set by WithAuditHook, actor is the actor of the
+	// mutation in progress.
+	auditHook func(op Op, key Key, actor string)
+	actor     string
+	// redactKey is set by WithKeyRedactor.
+	redactKey func(key Key) string
+	// writeLimiter is set by WithWriteLimiter.
+	writeLimiter Limiter
+
+	// baseline is reported against by Debug.
+	baseline memBaseline
+
+	// timers schedules the expirations.
+	timers          wheel
+	janitorInterval time.Duration
+	// janitor runs the janitor, if it was started.
+	janitor *periodic
+	closer
+
+	// loads deduplicates GetOrLoad calls.
+	loads loads
+	// stale is set by WithServeStaleOnError.
+	stale staleValues
+
+	stats stats
+
+	memWatcher *memWatcher
+	// pool is set by WithWorkers. spawned counts the tasks started by
+	// spawn which didn't return yet, it's accessed atomically.
+	pool    *workerPool
+	spawned int64
+	// steady is set by WithSteadyState.
+	steady *steadyState
+	// snaps is set by WithReadSnapshots.
+	snaps *snapshots
+	// space is closed once an entry is released or removed, waking the
+	// SetWait calls waiting for room, see freed.
+	space chan struct{}
+	// compactOnPurge is set by WithCompactOnPurge.
+	compactOnPurge bool
+	shrink         shrinker
+	// clock is set by WithCoarseClock.
+	clock *coarseClock
+
+	// gen counts the moves to the front of the list,
+	// frontElision is set by WithFrontElision.
+	gen          uint64
+	frontElision uint64
+	// seq counts the keys added, bySeq indexes them by seq,
+	// see KeysPage.
+	seq   uint64
+	bySeq seqIndex
 	//mutex does't require init
 	mu sync.Mutex
 }
@@ -43,137 +159,352 @@ type Key interface{}
 type entry struct {
 	key    Key
 	value  interface{}
+	// expire is when the entry expires on the nanotime clock,
+	// zero if it doesn't.
 	expire int64
+	// created is when the value was set.
+	created int64
+	// hits counts the lookups which returned the value.
+	hits uint64
+	// accessed is when the value was last returned or set.
+	accessed int64
+	// read is when the value was last returned from a read snapshot
+	// under WithMaxIdle, it's accessed atomically.
+	read int64
+	// gen is Cache.gen when the entry was last moved to the front.
+	gen uint64
+	// seq is Cache.seq when the key was added.
+	seq uint64
+	// refs counts the outstanding Acquire calls and removed is set
+	// once the entry has left the cache.
+	refs    int
+	removed bool
+	// setBy is recorded by WithProvenance.
+	setBy *callers
+	// onExpire is set by OnExpireSchedule.
+	onExpire func(key Key, value interface{})
+	// soft is set for entries added with SetWithExpire2.
+	soft softTTL
+	cost int64
+	// costIdx is the position of the entry in Cache.byCost.
+	costIdx int
+	// timer is the entry's node in the expiration wheel,
+	// in the bucket slot.
+	timer *list.Element
+	slot  int
+}
+
+// expired reports whether e has expired at now.
+func (e *entry) expired(now int64) bool {
+	return e.expire > 0 && now >= e.expire
 }
 
 // New creates a new Cache.
-// If maxEntries is zero, the cache has no limit and it's assumed
-// that eviction is done by the caller.
-func New(maxEntries int) *Cache {
-	return &Cache{
+// If maxEntries is zero or negative, the cache has no limit and it's
+// assumed that eviction is done by the caller. Unbounded makes that explicit,
+// NewBounded rejects it.
+func New(maxEntries int, opts ...Option) *Cache {
+	c := &Cache{
 		MaxEntries: maxEntries,
 		ll:         list.New(),
-		cache:      make(map[interface{}]*list.Element),
+		newStore:   NewMapStore,
+		shrink:     shrinker{ratio: defaultShrinkRatio},
+	}
+	c.done = make(chan struct{})
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.store = c.newStore()
+	c.baseline.read()
+	if c.janitorInterval > 0 {
+		c.startJanitor()
+	}
+	if c.memWatcher != nil {
+		c.every(c.memWatcher.interval, c.checkMemory)
 	}
+	if c.steady != nil {
+		c.every(c.steady.window, c.sampleSteady)
+	}
+	if c.rolling != nil {
+		c.sampleRolling()
+		c.every(rollingInterval, c.sampleRolling)
+	}
+	if c.clock != nil {
+		c.every(c.clock.interval, c.tickClock)
+	}
+	if c.snaps != nil {
+		c.every(time.Duration(c.snaps.quiet), c.publishSnapshot)
+	}
+	return c
 }
 
-// Add adds a value to the cache.
+// Set adds a value to the cache.
+// The entry expires after the TTL derived by WithTTLFromValue, if any,
+// the one set by WithDefaultTTL or the maximum TTL set by WithMaxTTL.
+// The write is dropped if the limiter set by WithWriteLimiter rejects it.
 func (c *Cache) Set(key Key, value interface{}) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.cache == nil {
-		c.cache = make(map[interface{}]*list.Element)
-		c.ll = list.New()
-	}
-	//the map type is not concurrency safe.
-	if ee, ok := c.cache[key]; ok {
-		c.ll.MoveToFront(ee)
-		ee.Value.(*entry).value = value
+	if !c.allowWrite() {
 		return
 	}
-	ele := c.ll.PushFront(&entry{
-		key:    key,
-		value:  value,
-		expire: 0,
-	})
-	c.cache[key] = ele
-	if c.MaxEntries != 0 && c.ll.Len() > c.MaxEntries+1 {
-		c.RemoveOldest()
-	}
+	// Set, SetWithExpire and the lookups are hot paths,
+	// they unlock explicitly rather than deferring it.
+	expire := c.expireAt(c.defaultTTL(value))
+	c.mu.Lock()
+	c.add(key, value, expire)
+	c.mu.Unlock()
 }
 
+// SetWithExpire adds a value to the cache which expires after expiretime.
+// The TTL is clamped to the bounds set by WithMinTTL and WithMaxTTL.
 func (c *Cache) SetWithExpire(key Key, value interface{}, expiretime time.Duration) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.cache == nil {
-		c.cache = make(map[interface{}]*list.Element)
-		c.ll = list.New()
-	}
-	//the map type is not concurrency safe.
-	if ee, ok := c.cache[key]; ok {
-		c.ll.MoveToFront(ee)
-		ee.Value.(*entry).value = value
+	if !c.allowWrite() {
 		return
 	}
-	ele := c.ll.PushFront(&entry{
-		key:    key,
-		value:  value,
-		expire: time.Now().Add(expiretime).Unix(),
-	})
-	c.cache[key] = ele
-	if c.MaxEntries != 0 && c.ll.Len() > c.MaxEntries+1 {
+	expire := c.expireAt(expiretime)
+	c.mu.Lock()
+	c.add(key, value, expire)
+	c.mu.Unlock()
+}
+
+// add inserts or updates the entry of key and evicts the oldest
+// entries if the cache is full. The caller must hold c.mu.
+func (c *Cache) add(key Key, value interface{}, expire int64) {
+	c.insert(key, value, expire)
+	c.evict()
+}
+
+// insert inserts or updates the entry of key and returns it, or nil
+// if the cache is draining or the type guard rejected the update.
+// The caller must hold c.mu.
+func (c *Cache) insert(key Key, value interface{}, expire int64) *entry {
+	if c.isDraining() {
+		return nil
+	}
+	if c.store == nil {
+		c.init()
+	}
+	c.stale.forget(key)
+	c.changed()
+	if c.cardinality != nil {
+		c.cardinality.add(key)
+	}
+	//the store is not concurrency safe.
+	if ee, ok := c.store.Get(key); ok {
+		e := ee.Value.(*entry)
+		if !c.allowType(e, value) {
+			return nil
+		}
+		c.touch(ee)
+		c.recordCallers(e)
+		c.audit(OpSet, key)
+		c.timers.remove(e)
+		// An unchanged value only has its expiration renewed.
+		if c.valueEqual == nil || !c.valueEqual(e.value, value) {
+			cost := c.entryCost(key, value)
+			atomic.AddInt64(&c.cost, cost-e.cost)
+			e.cost = cost
+			e.value = value
+			c.byCost.fix(e)
+			c.checkLarge(key, cost)
+			e.created = c.now()
+			e.hits = 0
+		}
+		e.accessed = c.now()
+		e.expire = c.capAge(e, expire)
+		e.soft = softTTL{}
+		if e.expire > 0 {
+			c.timers.add(e)
+		}
+		return e
+	}
+	if !c.allowNewType(key, value) {
+		return nil
+	}
+	now := c.now()
+	c.seq++
+	e := &entry{
+		seq:      c.seq,
+		key:      key,
+		value:    value,
+		expire:   expire,
+		created:  now,
+		accessed: now,
+		cost:     c.entryCost(key, value),
+	}
+	c.recordCallers(e)
+	c.audit(OpSet, key)
+	c.checkLarge(key, e.cost)
+	e.expire = c.capAge(e, expire)
+	if e.expire > 0 {
+		c.timers.add(e)
+	}
+	ele := c.ll.PushFront(e)
+	c.gen++
+	e.gen = c.gen
+	c.store.Set(key, ele)
+	c.bySeq.add(e)
+	c.byCost.add(e)
+	c.shrink.grew(c.ll.Len())
+	atomic.AddInt64(&c.size, 1)
+	atomic.AddInt64(&c.cost, e.cost)
+	return e
+}
+
+// evict removes the oldest entries until the cache is within
+// MaxEntries and MaxCost. The caller must hold c.mu.
+func (c *Cache) evict() {
+	for c.MaxEntries > 0 && c.ll.Len() > c.MaxEntries && c.ll.Len() > 0 {
+		c.stats.evictions++
 		c.RemoveOldest()
 	}
+	for c.MaxCost != 0 && atomic.LoadInt64(&c.cost) > c.MaxCost && c.ll.Len() > 0 {
+		c.stats.evictions++
+		c.RemoveOldest()
+	}
+	c.checkInvariants()
 }
 
 // Get looks up a key's value from the cache.
+// An expired entry is removed and reported as a miss.
 func (c *Cache) Get(key Key) (value interface{}, ok bool) {
-	//Visit the member of struct is safe.
-	//Don't worry about it.
-	if c.cache == nil {
-		return
+	if c.cardinality != nil {
+		c.cardinality.add(key)
 	}
+	if c.snaps != nil {
+		if value, ok, done := c.snaps.get(key, c.now()); done {
+			if ok {
+				c.hits.add(1)
+			} else {
+				c.misses.add(1)
+			}
+			c.prefixes.count(key, ok)
+			return value, ok
+		}
+	}
+	// The hit path must not allocate, see TestGetAllocs.
 	c.mu.Lock()
-	defer c.mu.Unlock()
-	if ele, hit := c.cache[key]; hit {
-		c.ll.MoveToFront(ele)
-		return ele.Value.(*entry).value, true
+	if c.store == nil {
+		c.mu.Unlock()
+		return
+	}
+	if ele, hit := c.store.Get(key); hit {
+		e := ele.Value.(*entry)
+		now := c.now()
+		if !e.expired(now) {
+			c.touch(ele)
+			c.hit(e, now)
+			value = e.value
+			c.mu.Unlock()
+			return value, true
+		}
+		c.expire(ele)
 	}
+	c.miss(key)
+	c.mu.Unlock()
 	return
 }
 
-// GetAndRemoveExpire loos up a key's value ,returns if it exists and call
-// a defer func to check it whether it's expired or not.
-// If it was expired,remove it
-func (c *Cache) GetAndRemoveExpire(key Key) (value interface{}, ok bool) {
-	//Visit the member of struct is safe.
-	//Don't worry about it.
-	if c.cache == nil {
+// GetIgnoreExpiry looks up a key's value from the cache,
+// returning it even if it has expired.
+func (c *Cache) GetIgnoreExpiry(key Key) (value interface{}, ok bool) {
+	c.mu.Lock()
+	if c.store == nil {
+		c.mu.Unlock()
 		return
 	}
+	if ele, hit := c.store.Get(key); hit {
+		c.touch(ele)
+		e := ele.Value.(*entry)
+		c.hit(e, c.now())
+		value = e.value
+		c.mu.Unlock()
+		return value, true
+	}
+	c.miss(key)
+	c.mu.Unlock()
+	return
+}
+
+// Peek looks up a key's value without updating its recency.
+// Expired entries are reported as a miss.
+func (c *Cache) Peek(key Key) (value interface{}, ok bool) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-	if ele, hit := c.cache[key]; hit {
-		if ele.Value.(*entry).expire > 0 {
-			defer func() {
-				if time.Now().Unix() >= ele.Value.(*entry).expire {
-					//No need to lock this.
-					//Because defer Unlock() wil run afer this function
-					c.removeElement(ele)
-					return
-				}
-			}()
+	if c.store == nil {
+		c.mu.Unlock()
+		return
+	}
+	if ele, hit := c.store.Get(key); hit {
+		if e := ele.Value.(*entry); !e.expired(c.now()) {
+			value, ok = e.value, true
 		}
-		c.ll.MoveToFront(ele)
-		return ele.Value.(*entry).value, true
 	}
+	c.mu.Unlock()
 	return
 }
 
-func (c *Cache) Has(key Key) (hit bool) {
-	if c.cache == nil {
+// GetFresh looks up a key's value from the cache. An expired entry
+// is removed before returning and reported with ok set to false,
+// so callers never receive dead data.
+// It behaves like Get and replaces GetAndRemoveExpire.
+func (c *Cache) GetFresh(key Key) (value interface{}, ok bool) {
+	return c.Get(key)
+}
+
+// GetAndRemoveExpire looks up a key's value and removes it if it has expired.
+//
+// Deprecated: GetAndRemoveExpire used to return an expired value once
+// before removing it. It now behaves like GetFresh, use that instead.
+func (c *Cache) GetAndRemoveExpire(key Key) (value interface{}, ok bool) {
+	return c.GetFresh(key)
+}
+
+// Exists reports whether key is in the cache and hasn't expired,
+// without updating its recency or the statistics, like Peek.
+func (c *Cache) Exists(key Key) (hit bool) {
+	c.mu.Lock()
+	if c.store == nil {
+		c.mu.Unlock()
 		return
 	}
-	//It's safe to read the map only.
-	_, hit = c.cache[key]
+	if ele, ok := c.store.Get(key); ok {
+		hit = !ele.Value.(*entry).expired(c.now())
+	}
+	c.mu.Unlock()
 	return
 }
 
+// Has reports whether key is in the cache and hasn't expired.
+//
+// Deprecated: Has used to read the store without locking, racing with
+// writers. It's now an alias of Exists, which should be used instead.
+func (c *Cache) Has(key Key) bool {
+	return c.Exists(key)
+}
+
 // Remove removes the provided key from the cache.
 func (c *Cache) Remove(key Key) {
-	if c.cache == nil {
+	c.mu.Lock()
+	if c.store == nil {
+		c.mu.Unlock()
 		return
 	}
-	c.mu.Lock()
-	if ele, hit := c.cache[key]; hit {
+	c.remove(key)
+	c.mu.Unlock()
+}
+
+// remove removes key. The caller must hold c.mu.
+func (c *Cache) remove(key Key) {
+	if ele, hit := c.store.Get(key); hit {
 		c.removeElement(ele)
+		c.audit(OpRemove, key)
 	}
-	c.mu.Unlock()
+	c.stale.forget(key)
+	c.checkInvariants()
 }
 
 // RemoveOldest removes the oldest item from the cache.
 func (c *Cache) RemoveOldest() {
-	if c.cache == nil {
+	if c.store == nil {
 		return
 	}
 	ele := c.ll.Back()
@@ -185,51 +516,138 @@ func (c *Cache) RemoveOldest() {
 func (c *Cache) removeElement(e *list.Element) {
 	c.ll.Remove(e)
 	kv := e.Value.(*entry)
-	delete(c.cache, kv.key)
-	if c.OnEvicted != nil {
-		c.OnEvicted(kv.key, kv.value)
+	c.changed()
+	c.stats.removed(kv, c.now())
+	atomic.AddInt64(&c.size, -1)
+	atomic.AddInt64(&c.cost, -kv.cost)
+	c.timers.remove(kv)
+	c.store.Delete(kv.key)
+	c.departed(kv)
+	c.finalize(kv)
+	c.bySeq.removed()
+	c.byCost.remove(kv)
+	if c.shrink.shrunk(c.ll.Len()) {
+		c.rebuildStore()
 	}
 }
 
-// Len returns the number of items in the cache.
+// ApproxLen returns the number of items in the cache without taking
+// the lock, for gauges on hot paths. Unlike Len, it includes expired
+// items which haven't been removed yet.
+func (c *Cache) ApproxLen() int {
+	return int(atomic.LoadInt64(&c.size))
+}
+
+// Len returns the number of unexpired items in the cache.
 func (c *Cache) Len() int {
-	if c.cache == nil {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil {
 		return 0
 	}
+	c.removeExpired()
 	return c.ll.Len()
 }
 
 // Clear purges all stored items from the cache.
 func (c *Cache) Clear() {
+	c.ClearContext(context.Background())
+}
+
+// ClearContext is like Clear, passing ctx to the callback set by
+// WithOnEvictedContext, e.g. so write-back handlers finish or abandon
+// their work by a shutdown deadline.
+func (c *Cache) ClearContext(ctx context.Context) {
+	defer c.afterPurge()
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if c.OnEvicted != nil {
-		for _, e := range c.cache {
-			kv := e.Value.(*entry)
-			c.OnEvicted(kv.key, kv.value)
-		}
+	c.audit(OpClear, nil)
+	c.changed()
+	if (c.OnEvicted != nil || c.onEvictedCtx != nil) && c.store != nil {
+		c.evictCtx = ctx
+		defer func() { c.evictCtx = nil }()
+		c.store.Range(func(_ Key, e *list.Element) bool {
+			c.finalize(e.Value.(*entry))
+			return true
+		})
 	}
 	c.ll = nil
-	c.cache = nil
+	c.store = nil
+	c.bySeq.reset()
+	c.byCost.reset()
+	if c.typeGuard != nil {
+		c.typeGuard.departed.Clear()
+	}
+	c.stale.reset()
+	c.shrink.peak = 0
+	atomic.StoreInt64(&c.size, 0)
+	atomic.StoreInt64(&c.cost, 0)
+	c.timers.reset()
 }
 
 //Reset all cache value and clear all key.
 func (c *Cache) Reset() {
+	defer c.afterPurge()
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	for _, e := range c.cache {
+	if c.store == nil {
+		return
+	}
+	for e := c.ll.Back(); e != nil; e = c.ll.Back() {
 		c.removeElement(e)
 	}
+	if c.typeGuard != nil {
+		c.typeGuard.departed.Clear()
+	}
+	c.audit(OpClear, nil)
+	c.stale.reset()
+	c.checkInvariants()
 }
 
+// RemoveExpire removes all expired items from the cache.
 func (c *Cache) RemoveExpire() {
-	for _, e := range c.cache {
-		if e.Value.(*entry).expire > 0 {
-			if time.Now().Unix() >= e.Value.(*entry).expire {
-				c.mu.Lock()
-				c.removeElement(e)
-				c.mu.Unlock()
-			}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil {
+		return
+	}
+	c.removeExpired()
+}
+
+// removeExpired removes the entries which came due on the
+// expiration wheel. The caller must hold c.mu.
+func (c *Cache) removeExpired() {
+	now := c.now()
+	c.timers.advance(now, func(e *entry) {
+		if ele, ok := c.store.Get(e.key); ok {
+			c.expire(ele)
 		}
+	})
+	c.removeIdle(now)
+	c.stale.prune(now)
+	c.checkInvariants()
+}
+
+// expire removes the expired element e. The caller must hold c.mu.
+func (c *Cache) expire(e *list.Element) {
+	c.stats.expirations++
+	c.removeElement(e)
+	kv := e.Value.(*entry)
+	if c.maxHits == 0 || kv.hits < c.maxHits {
+		// Values spent by WithMaxHits are never served again.
+		c.stale.keep(kv, c.now(), c.MaxEntries)
+	}
+	if kv.onExpire != nil {
+		fn, key, value := kv.onExpire, kv.key, kv.value
+		c.spawn(func() { fn(key, value) })
+	}
+}
+
+// init allocates the list and the store after a Clear.
+func (c *Cache) init() {
+	c.ll = list.New()
+	if c.newStore == nil {
+		c.newStore = NewMapStore
 	}
+	c.store = c.newStore()
 }
@@ -1,6 +1,9 @@
 package cache
 
 import (
+	"context"
+	"math/rand"
+	"sync"
 	"testing"
 	"time"
 )
@@ -57,3 +60,260 @@ func BenchMark(b *testing.B) {
 	ce.Clear()
 
 }
+
+// TestClearConcurrent races the lookups against Clear, which drops the
+// store: they must check for it with the lock held.
+func TestNegativeMaxEntries(t *testing.T) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c := New(-1)
+		for i := 0; i < 10; i++ {
+			c.Set(i, i)
+		}
+		if c.Len() != 10 {
+			t.Errorf("Len() = %d, want 10 without a limit", c.Len())
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Set hangs with a negative MaxEntries")
+	}
+}
+
+func TestClearConcurrent(t *testing.T) {
+	ce := New(16)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			ce.Get("a")
+			ce.GetIgnoreExpiry("a")
+			ce.Peek("a")
+			ce.GetResult("a")
+			ce.Members("a")
+			ce.Remove("b")
+			ce.RemoveContext(context.Background(), "b")
+			if _, release, ok := ce.Acquire("a"); ok {
+				release()
+			}
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		ce.Set("a", []interface{}{i})
+		ce.Clear()
+	}
+	close(done)
+	wg.Wait()
+}
+
+// TestExistsConcurrent races Exists against writers and Clear, for
+// the race detector: Has used to read the store without locking.
+func TestExistsConcurrent(t *testing.T) {
+	ce := New(16)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				ce.Set(j%32, j)
+				ce.Remove((j + 7) % 32)
+				if j%100 == 0 {
+					ce.Clear()
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				ce.Exists(j % 32)
+				ce.Has(j % 32)
+			}
+		}()
+	}
+	wg.Wait()
+	ce.Set("a", 1)
+	if !ce.Exists("a") || ce.Exists("b") {
+		t.Fatal("Exists is wrong after the writers stopped")
+	}
+}
+
+func TestExpiryConsistency(t *testing.T) {
+	ce := New(10)
+	ce.SetWithExpire("a", 1, 10*time.Millisecond)
+	ce.Set("b", 2)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok := ce.Peek("a"); ok {
+		t.Error("Peek returned an expired value")
+	}
+	if ce.Has("a") || ce.Exists("a") {
+		t.Error("Has or Exists reported an expired key")
+	}
+	if v, ok := ce.GetIgnoreExpiry("a"); !ok || v != 1 {
+		t.Errorf("GetIgnoreExpiry = %v, %v, want 1, true", v, ok)
+	}
+	if _, ok := ce.Get("a"); ok {
+		t.Error("Get returned an expired value")
+	}
+	if _, ok := ce.GetIgnoreExpiry("a"); ok {
+		t.Error("Get should remove the expired entry")
+	}
+	if n := ce.Len(); n != 1 {
+		t.Errorf("Len = %d, want 1", n)
+	}
+}
+
+func TestGetFresh(t *testing.T) {
+	ce := New(10)
+	ce.SetWithExpire("a", 1, 10*time.Millisecond)
+	if v, ok := ce.GetFresh("a"); !ok || v != 1 {
+		t.Fatalf("GetFresh = %v, %v, want 1, true", v, ok)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if v, ok := ce.GetFresh("a"); ok {
+		t.Fatalf("GetFresh returned the expired value %v", v)
+	}
+	if _, ok := ce.GetIgnoreExpiry("a"); ok {
+		t.Fatal("GetFresh should remove the expired entry")
+	}
+}
+
+func TestLenAfterClear(t *testing.T) {
+	ce := New(10)
+	ce.Set("a", 1)
+	ce.Set("b", 2)
+	if n := ce.ApproxLen(); n != 2 {
+		t.Errorf("ApproxLen = %d, want 2", n)
+	}
+	ce.Remove("a")
+	if n := ce.ApproxLen(); n != 1 {
+		t.Errorf("ApproxLen = %d, want 1", n)
+	}
+	ce.Clear()
+	if n, an := ce.Len(), ce.ApproxLen(); n != 0 || an != 0 {
+		t.Errorf("Len, ApproxLen = %d, %d after Clear", n, an)
+	}
+}
+
+func TestConcurrentLen(t *testing.T) {
+	ce := New(100)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			ce.Set(i, i)
+			if i%100 == 0 {
+				ce.Clear()
+			}
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		ce.Len()
+		ce.ApproxLen()
+	}
+	<-done
+}
+
+func TestGetAllocs(t *testing.T) {
+	ce := New(10)
+	var key Key = "hot"
+	ce.SetWithExpire(key, 1, time.Hour)
+	allocs := testing.AllocsPerRun(1000, func() {
+		ce.Get(key)
+	})
+	if allocs != 0 {
+		t.Fatalf("Get hit allocated %v times, want 0", allocs)
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	ce := New(10)
+	var key Key = "hot"
+	ce.Set(key, 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ce.Get(key)
+	}
+}
+
+func BenchmarkExists(b *testing.B) {
+	ce := New(10)
+	var key Key = "hot"
+	ce.Set(key, 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ce.Exists(key)
+	}
+}
+
+func BenchmarkSet(b *testing.B) {
+	ce := New(1 << 10)
+	keys := make([]Key, 1<<12)
+	for i := range keys {
+		keys[i] = i
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ce.Set(keys[i&(1<<12-1)], i)
+	}
+}
+
+func BenchmarkSetWithExpire(b *testing.B) {
+	ce := New(1 << 10)
+	keys := make([]Key, 1<<12)
+	for i := range keys {
+		keys[i] = i
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ce.SetWithExpire(keys[i&(1<<12-1)], i, time.Minute)
+	}
+}
+
+// BenchmarkZipf replays a Zipf distributed trace, reporting the hit
+// ratio along with the throughput, for comparisons between stores.
+// The bench module compares the cache against other implementations.
+func BenchmarkZipf(b *testing.B) {
+	for _, bm := range []struct {
+		name     string
+		newStore func() Store
+	}{
+		{"Map", NewMapStore},
+		{"Robin", NewRobinStore},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			z := rand.NewZipf(rand.New(rand.NewSource(1)), 1.01, 1, 1<<20)
+			trace := make([]Key, 1<<16)
+			for i := range trace {
+				trace[i] = z.Uint64()
+			}
+			ce := New(1<<12, WithStore(bm.newStore))
+			var hits int
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				key := trace[i&(1<<16-1)]
+				if _, ok := ce.Get(key); ok {
+					hits++
+				} else {
+					ce.Set(key, i)
+				}
+			}
+			b.ReportMetric(float64(hits)/float64(b.N), "hits/op")
+		})
+	}
+}
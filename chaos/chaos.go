@@ -0,0 +1,89 @@
+// Package chaos wraps a cache to make it misbehave, for testing that
+// applications cope with slow caches, lost writes and evicted entries.
+// It isn't meant for production use.
+package chaos
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	cache "github.com/MeteorsLiu/LRUCache"
+)
+
+// Config sets the faults to inject. Rates are probabilities from 0 to 1.
+type Config struct {
+	// Seed seeds the schedule of the faults, so a failing test can be
+	// reproduced exactly.
+	Seed int64
+	// Latency is the maximum delay added to every operation.
+	Latency time.Duration
+	// DropRate is the rate of writes silently dropped.
+	DropRate float64
+	// MissRate is the rate of lookups which miss anyway.
+	MissRate float64
+}
+
+// Cache is a cache.Cacher injecting faults into the operations
+// of the cache it wraps.
+type Cache struct {
+	c   cache.Cacher
+	cfg Config
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// New returns c wrapped to inject the faults of cfg.
+func New(c cache.Cacher, cfg Config) *Cache {
+	return &Cache{c: c, cfg: cfg, rand: rand.New(rand.NewSource(cfg.Seed))}
+}
+
+// fault draws the delay of the next operation and whether it fails
+// with probability rate.
+func (ch *Cache) fault(rate float64) (time.Duration, bool) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	var delay time.Duration
+	if ch.cfg.Latency > 0 {
+		delay = time.Duration(ch.rand.Int63n(int64(ch.cfg.Latency) + 1))
+	}
+	return delay, ch.rand.Float64() < rate
+}
+
+func (ch *Cache) inject(rate float64) bool {
+	delay, fail := ch.fault(rate)
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return fail
+}
+
+// Get looks up a key's value, or misses.
+func (ch *Cache) Get(key cache.Key) (interface{}, bool) {
+	if ch.inject(ch.cfg.MissRate) {
+		return nil, false
+	}
+	return ch.c.Get(key)
+}
+
+// Set adds a value, or drops it.
+func (ch *Cache) Set(key cache.Key, value interface{}) {
+	if !ch.inject(ch.cfg.DropRate) {
+		ch.c.Set(key, value)
+	}
+}
+
+// SetWithExpire adds a value which expires after ttl, or drops it.
+func (ch *Cache) SetWithExpire(key cache.Key, value interface{}, ttl time.Duration) {
+	if !ch.inject(ch.cfg.DropRate) {
+		ch.c.SetWithExpire(key, value, ttl)
+	}
+}
+
+// Remove removes key. Removals aren't dropped, as a cache which keeps
+// invalidated entries would serve stale data rather than misbehave.
+func (ch *Cache) Remove(key cache.Key) {
+	ch.inject(0)
+	ch.c.Remove(key)
+}
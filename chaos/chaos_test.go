@@ -0,0 +1,54 @@
+package chaos
+
+import (
+	"testing"
+	"time"
+
+	cache "github.com/MeteorsLiu/LRUCache"
+)
+
+var _ cache.Cacher = (*Cache)(nil)
+
+// run returns the outcome of the same operations on a cache
+// wrapped with cfg.
+func run(cfg Config) []bool {
+	ch := New(cache.New(0), cfg)
+	var hits []bool
+	for i := 0; i < 100; i++ {
+		ch.Set(i, i)
+		_, ok := ch.Get(i)
+		hits = append(hits, ok)
+	}
+	return hits
+}
+
+func TestChaos(t *testing.T) {
+	cfg := Config{Seed: 42, DropRate: 0.2, MissRate: 0.2}
+	a, b := run(cfg), run(cfg)
+	misses := 0
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatal("the same seed gave different faults")
+		}
+		if !a[i] {
+			misses++
+		}
+	}
+	if misses < 20 || misses > 60 {
+		t.Fatalf("%d misses out of 100, want about 36", misses)
+	}
+	for _, ok := range run(Config{}) {
+		if !ok {
+			t.Fatal("a fault was injected without any configured")
+		}
+	}
+
+	ch := New(cache.New(0), Config{Latency: 2 * time.Millisecond})
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		ch.Get(i)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("latency exceeded its bound")
+	}
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// epoch is the origin of the cache clock.
+var epoch = time.Now()
+
+// nanotime returns the monotonic nanoseconds since epoch. Expirations
+// are compared on this clock, so they are precise to the nanosecond
+// and immune to wall clock jumps.
+func nanotime() int64 {
+	return int64(time.Since(epoch))
+}
+
+// wallTime converts t from nanotime to wall clock time.
+func wallTime(t int64) time.Time {
+	return epoch.Add(time.Duration(t))
+}
+
+// coarseClock caches nanotime, refreshed by a ticker.
+type coarseClock struct {
+	// now is accessed atomically. It's clockStopped once the ticker
+	// stopped, the time is then read from nanotime again.
+	now      int64
+	interval time.Duration
+}
+
+// WithCoarseClock makes the cache read the time from a clock updated
+// every interval (e.g. a millisecond) instead of on every operation,
+// cutting the clock reads of very hot caches. Expirations are then
+// only precise to the interval. The clock runs until Close is called,
+// the cache then reads the time on every operation again.
+// It panics if interval isn't positive.
+func WithCoarseClock(interval time.Duration) Option {
+	if interval <= 0 {
+		panic("cache: WithCoarseClock interval must be positive")
+	}
+	return func(c *Cache) {
+		c.clock = &coarseClock{now: nanotime(), interval: interval}
+	}
+}
+
+// clockStopped marks a coarse clock which isn't updated anymore.
+const clockStopped = -1
+
+// now returns the current time on the nanotime clock.
+func (c *Cache) now() int64 {
+	if c.clock != nil {
+		if t := atomic.LoadInt64(&c.clock.now); t != clockStopped {
+			return t
+		}
+	}
+	return nanotime()
+}
+
+// tickClock updates the coarse clock, unless Close stopped it.
+func (c *Cache) tickClock() {
+	if t := atomic.LoadInt64(&c.clock.now); t != clockStopped {
+		atomic.CompareAndSwapInt64(&c.clock.now, t, nanotime())
+	}
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCoarseClock(t *testing.T) {
+	ce := New(10, WithCoarseClock(time.Millisecond))
+	defer ce.Close()
+	start := ce.now()
+	ce.SetWithExpire("a", 1, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if ce.now() == start {
+		t.Fatal("the coarse clock didn't advance")
+	}
+	if _, ok := ce.Get("a"); ok {
+		t.Fatal("entry outlived its TTL on the coarse clock")
+	}
+}
+
+func TestCoarseClockClosed(t *testing.T) {
+	ce := New(10, WithCoarseClock(time.Millisecond))
+	ce.Close()
+	// The clock stops asynchronously.
+	time.Sleep(5 * time.Millisecond)
+	ce.SetWithExpire("a", 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := ce.Get("a"); ok {
+		t.Fatal("entry outlived its TTL after Close")
+	}
+}
+
+func TestCoarseClockZeroInterval(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("WithCoarseClock with a zero interval didn't panic")
+		}
+	}()
+	New(10, WithCoarseClock(0))
+}
+
+func BenchmarkGetCoarseClock(b *testing.B) {
+	ce := New(10, WithCoarseClock(time.Millisecond))
+	defer ce.Close()
+	var key Key = "hot"
+	ce.Set(key, 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ce.Get(key)
+	}
+}
@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrNotCollection is returned when AppendTo or AddToSet find a value
+// which isn't a list or a set respectively.
+var ErrNotCollection = errors.New("cache: value is not a collection")
+
+// Set values are stored as map[interface{}]struct{}.
+type set = map[interface{}]struct{}
+
+// AppendTo appends elem to the []interface{} stored for key under the
+// lock, creating it if key is missing, so callers caching small lists
+// don't race on read-modify-write cycles.
+func (c *Cache) AppendTo(key Key, elem interface{}) error {
+	return c.modify(key, func(v interface{}, ok bool) (interface{}, error) {
+		if !ok {
+			return []interface{}{elem}, nil
+		}
+		l, isList := v.([]interface{})
+		if !isList {
+			return nil, ErrNotCollection
+		}
+		// Copy on write, the list may be in use by readers.
+		nl := make([]interface{}, len(l), len(l)+1)
+		copy(nl, l)
+		return append(nl, elem), nil
+	})
+}
+
+// AddToSet adds elem to the set stored for key under the lock,
+// creating it if key is missing. elem must be comparable. Like for
+// AppendTo, the set is copied.
+func (c *Cache) AddToSet(key Key, elem interface{}) error {
+	return c.modify(key, func(v interface{}, ok bool) (interface{}, error) {
+		if !ok {
+			return set{elem: {}}, nil
+		}
+		s, isSet := v.(set)
+		if !isSet {
+			return nil, ErrNotCollection
+		}
+		// Copy on write, the set may be in use by readers.
+		ns := make(set, len(s)+1)
+		for m := range s {
+			ns[m] = struct{}{}
+		}
+		ns[elem] = struct{}{}
+		return ns, nil
+	})
+}
+
+// Members returns a copy of the list or set stored for key.
+// Set members are in no particular order. A value which isn't a list
+// nor a set is reported as a miss, and isn't counted as a hit.
+func (c *Cache) Members(key Key) ([]interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil {
+		return nil, false
+	}
+	ele, hit := c.store.Get(key)
+	if !hit {
+		c.miss(key)
+		return nil, false
+	}
+	e := ele.Value.(*entry)
+	now := c.now()
+	if e.expired(now) {
+		c.expire(ele)
+		c.miss(key)
+		return nil, false
+	}
+	var members []interface{}
+	switch v := e.value.(type) {
+	case []interface{}:
+		members = append([]interface{}(nil), v...)
+	case set:
+		members = make([]interface{}, 0, len(v))
+		for m := range v {
+			members = append(members, m)
+		}
+	default:
+		// Not a collection, the lookup doesn't use the entry.
+		c.miss(key)
+		return nil, false
+	}
+	c.touch(ele)
+	c.hit(e, now)
+	return members, true
+}
+
+// modify replaces the value of key by the one fn returns under the
+// lock. Missing and expired keys are passed to fn with ok false and
+// added with the default TTL.
+func (c *Cache) modify(key Key, fn func(v interface{}, ok bool) (interface{}, error)) error {
+	return c.modifyWithExpire(key, -1, fn)
+}
+
+// modifyWithExpire is like modify, adding missing keys with expire
+// instead, unless it's negative.
+func (c *Cache) modifyWithExpire(key Key, expire int64, fn func(v interface{}, ok bool) (interface{}, error)) error {
+	if c.isDraining() {
+		return ErrDraining
+	}
+	if !c.allowWrite() {
+		return ErrWriteLimited
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store != nil {
+		if ele, hit := c.store.Get(key); hit {
+			e := ele.Value.(*entry)
+			if !e.expired(c.now()) {
+				v, err := fn(e.value, true)
+				if err != nil {
+					return err
+				}
+				c.touch(ele)
+				cost := c.entryCost(key, v)
+				atomic.AddInt64(&c.cost, cost-e.cost)
+				e.cost, e.value = cost, v
+				c.byCost.fix(e)
+				c.checkLarge(key, cost)
+				c.changed()
+				c.audit(OpSet, key)
+				c.evict()
+				return nil
+			}
+			c.expire(ele)
+		}
+	}
+	v, err := fn(nil, false)
+	if err != nil {
+		return err
+	}
+	if expire < 0 {
+		expire = c.expireAt(c.defaultTTL(v))
+	}
+	c.add(key, v, expire)
+	return nil
+}
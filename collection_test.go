@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestCollections(t *testing.T) {
+	ce := New(10)
+	for i := 0; i < 3; i++ {
+		if err := ce.AppendTo("list", i); err != nil {
+			t.Fatal(err)
+		}
+		ce.AddToSet("set", i%2)
+	}
+	l, ok := ce.Members("list")
+	if !ok || len(l) != 3 || l[0] != 0 || l[2] != 2 {
+		t.Fatalf("Members(list) = %v, %v", l, ok)
+	}
+	l[0] = "mutated"
+	if l, _ := ce.Members("list"); l[0] != 0 {
+		t.Fatal("Members didn't return a copy")
+	}
+	s, _ := ce.Members("set")
+	ints := []int{s[0].(int), s[1].(int)}
+	sort.Ints(ints)
+	if len(s) != 2 || ints[0] != 0 || ints[1] != 1 {
+		t.Fatalf("Members(set) = %v", s)
+	}
+
+	ce.Set("plain", 1)
+	if err := ce.AppendTo("plain", 2); err != ErrNotCollection {
+		t.Fatalf("AppendTo(plain) = %v, want ErrNotCollection", err)
+	}
+	if err := ce.AddToSet("list", 2); err != ErrNotCollection {
+		t.Fatalf("AddToSet(list) = %v, want ErrNotCollection", err)
+	}
+}
+
+func TestCollectionsCopyOnWrite(t *testing.T) {
+	ce := New(10)
+	ce.AppendTo("list", 0)
+	ce.AddToSet("set", 0)
+	v, _ := ce.Get("list")
+	l := v.([]interface{})
+	v, _ = ce.Get("set")
+	s := v.(set)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i < 100; i++ {
+			ce.AppendTo("list", i)
+			ce.AddToSet("set", i)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		for range s {
+		}
+		_ = l[0]
+	}
+	<-done
+	if len(l) != 1 || len(s) != 1 {
+		t.Fatalf("the values returned by Get were modified: %v, %v", l, s)
+	}
+}
+
+func TestMembersNotCollection(t *testing.T) {
+	ce := New(10, WithMaxHits(1))
+	ce.Set("token", "secret")
+	if m, ok := ce.Members("token"); ok || m != nil {
+		t.Fatalf("Members(token) = %v, %v, want a miss", m, ok)
+	}
+	if s := ce.Stats(); s.Hits != 0 || s.Misses != 1 {
+		t.Fatalf("Stats = %d hits, %d misses, want 0, 1", s.Hits, s.Misses)
+	}
+	// The lookup didn't spend the only hit of the token.
+	if v, ok := ce.Get("token"); !ok || v != "secret" {
+		t.Fatalf("Get = %v, %v", v, ok)
+	}
+}
@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config holds the parameters of a Cache, for creating it from decoded
+// config files with NewFromConfig and tuning it while it's in use with
+// ApplyConfig. Zero values mean no limit, no default and no janitor,
+// like for the corresponding options.
+type Config struct {
+	MaxEntries int   `json:"max_entries" yaml:"max_entries"`
+	MaxCost    int64 `json:"max_cost" yaml:"max_cost"`
+	// DefaultTTL, MinTTL and MaxTTL are set like WithDefaultTTL,
+	// WithMinTTL and WithMaxTTL. They apply to the entries added
+	// afterwards, the existing ones keep their expiration.
+	DefaultTTL time.Duration `json:"default_ttl" yaml:"default_ttl"`
+	MinTTL     time.Duration `json:"min_ttl" yaml:"min_ttl"`
+	MaxTTL     time.Duration `json:"max_ttl" yaml:"max_ttl"`
+	// JanitorInterval is set like WithJanitor.
+	JanitorInterval time.Duration `json:"janitor_interval" yaml:"janitor_interval"`
+}
+
+// Validate reports the first invalid parameter of cfg:
+// a negative one or a MinTTL above MaxTTL.
+func (cfg Config) Validate() error {
+	for _, p := range []struct {
+		name string
+		v    int64
+	}{
+		{"MaxEntries", int64(cfg.MaxEntries)},
+		{"MaxCost", cfg.MaxCost},
+		{"DefaultTTL", int64(cfg.DefaultTTL)},
+		{"MinTTL", int64(cfg.MinTTL)},
+		{"MaxTTL", int64(cfg.MaxTTL)},
+		{"JanitorInterval", int64(cfg.JanitorInterval)},
+	} {
+		if p.v < 0 {
+			return fmt.Errorf("cache: negative %s %d", p.name, p.v)
+		}
+	}
+	if cfg.MaxTTL > 0 && cfg.MinTTL > cfg.MaxTTL {
+		return fmt.Errorf("cache: MinTTL %v above MaxTTL %v", cfg.MinTTL, cfg.MaxTTL)
+	}
+	return nil
+}
+
+// NewFromConfig creates a Cache configured by cfg, after validating it.
+// opts are applied after cfg and configure what it doesn't cover.
+func NewFromConfig(cfg Config, opts ...Option) (*Cache, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfgOpts := []Option{
+		WithMaxCost(cfg.MaxCost),
+		WithDefaultTTL(cfg.DefaultTTL),
+		WithMinTTL(cfg.MinTTL),
+		WithMaxTTL(cfg.MaxTTL),
+	}
+	if cfg.JanitorInterval > 0 {
+		cfgOpts = append(cfgOpts, WithJanitor(cfg.JanitorInterval))
+	}
+	return New(cfg.MaxEntries, append(cfgOpts, opts...)...), nil
+}
+
+// Config returns the current configuration of the cache.
+func (c *Cache) Config() Config {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b := c.ttlBounds()
+	return Config{
+		MaxEntries:      c.MaxEntries,
+		MaxCost:         c.MaxCost,
+		DefaultTTL:      b.def,
+		MinTTL:          b.min,
+		MaxTTL:          b.max,
+		JanitorInterval: c.janitorInterval,
+	}
+}
+
+// ApplyConfig changes the configuration of the cache in place, so it
+// can be tuned from a config system without a restart. It returns
+// the error of cfg.Validate, without applying anything, if any. Lowering the
+// limits evicts the oldest entries right away. Changing the janitor
+// interval reschedules every expiration on the new wheel resolution,
+// which is O(n).
+func (c *Cache) ApplyConfig(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.MaxEntries = cfg.MaxEntries
+	c.MaxCost = cfg.MaxCost
+	c.updateTTLs(func(b *ttlBounds) {
+		b.def, b.min, b.max = cfg.DefaultTTL, cfg.MinTTL, cfg.MaxTTL
+	})
+	if cfg.JanitorInterval != c.janitorInterval {
+		c.setJanitor(cfg.JanitorInterval)
+	}
+	c.evict()
+	return nil
+}
+
+// setJanitor changes the janitor interval. The caller must hold c.mu.
+func (c *Cache) setJanitor(interval time.Duration) {
+	c.janitorInterval = interval
+	switch {
+	case interval <= 0:
+		if c.janitor != nil {
+			c.janitor.stop()
+		}
+	case c.janitor == nil:
+		c.startJanitor()
+	default:
+		c.janitor.reset(interval)
+	}
+
+	tick := int64(interval)
+	if tick <= 0 {
+		tick = defaultTick
+	}
+	if c.timers.buckets == nil {
+		c.timers.tick = tick
+		return
+	}
+	old := c.timers.buckets
+	c.timers = wheel{tick: tick}
+	for i := range old {
+		for el := old[i].Front(); el != nil; el = el.Next() {
+			c.timers.add(el.Value.(*entry))
+		}
+	}
+}
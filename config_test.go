@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestApplyConfig(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	for i := 0; i < 10; i++ {
+		c.SetWithExpire(i, i, time.Hour)
+	}
+	c.ApplyConfig(Config{MaxEntries: 4, DefaultTTL: 5 * time.Millisecond, JanitorInterval: time.Millisecond})
+	if n := c.Len(); n > 4 {
+		t.Fatalf("Len() = %d after lowering MaxEntries", n)
+	}
+	if _, ok := c.Get(9); !ok {
+		t.Fatal("newest entry was evicted")
+	}
+	if got := c.Config(); got.MaxEntries != 4 || got.JanitorInterval != time.Millisecond {
+		t.Fatalf("Config() = %+v", got)
+	}
+
+	c.Set("short", 1)
+	time.Sleep(50 * time.Millisecond)
+	if n := c.ApproxLen(); n != 3 {
+		t.Fatalf("ApproxLen() = %d, the janitor didn't remove the entry with the default TTL", n)
+	}
+
+	// The rescheduled entries still expire.
+	c.ApplyConfig(Config{MaxEntries: 4, JanitorInterval: 2 * time.Millisecond})
+	c.SetWithExpire("short", 1, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := c.Peek("short"); ok {
+		t.Fatal("entry didn't expire after the janitor interval changed")
+	}
+	c.ApplyConfig(Config{})
+	c.Set("forever", 1)
+	if _, ok := c.Get("forever"); !ok {
+		t.Fatal("entry without TTL missing")
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	for _, cfg := range []Config{
+		{MaxEntries: -1},
+		{MaxCost: -1},
+		{JanitorInterval: -time.Second},
+		{MinTTL: time.Hour, MaxTTL: time.Minute},
+	} {
+		if _, err := NewFromConfig(cfg); err == nil {
+			t.Errorf("NewFromConfig(%+v) succeeded", cfg)
+		}
+	}
+
+	var cfg Config
+	if err := json.Unmarshal([]byte(`{"max_entries": 2, "default_ttl": 1000000}`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if got := c.Config(); got != cfg {
+		t.Fatalf("Config() = %+v, want %+v", got, cfg)
+	}
+	c.Set("a", 1)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("entry outlived the default TTL")
+	}
+	if err := c.ApplyConfig(Config{MaxEntries: -1}); err == nil || c.Config().MaxEntries != 2 {
+		t.Fatal("ApplyConfig applied an invalid config")
+	}
+}
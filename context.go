@@ -0,0 +1,18 @@
+package cache
+
+import "context"
+
+// contextKey is the key of the cache in a context.
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying c, e.g. for middleware
+// handing a request or tenant scoped cache down to handlers.
+func NewContext(ctx context.Context, c *Cache) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the cache carried by ctx, if any.
+func FromContext(ctx context.Context) (*Cache, bool) {
+	c, ok := ctx.Value(contextKey{}).(*Cache)
+	return c, ok
+}
@@ -0,0 +1,17 @@
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Fatal("FromContext found a cache in an empty context")
+	}
+	c := New(10)
+	ctx := NewContext(context.Background(), c)
+	if got, ok := FromContext(ctx); !ok || got != c {
+		t.Fatal("FromContext didn't return the cache")
+	}
+}
@@ -0,0 +1,33 @@
+package cache
+
+import "sync/atomic"
+
+// WithCost sets the function computing the cost of an entry, e.g. the
+// size of its value in bytes, which is bounded by MaxCost.
+// Without it every entry costs 1.
+func WithCost(cost func(key Key, value interface{}) int64) Option {
+	return func(c *Cache) {
+		c.costOf = cost
+		c.byCost = &costIndex{}
+	}
+}
+
+// WithMaxCost sets MaxCost.
+func WithMaxCost(max int64) Option {
+	return func(c *Cache) {
+		c.MaxCost = max
+	}
+}
+
+func (c *Cache) entryCost(key Key, value interface{}) int64 {
+	if c.costOf == nil {
+		return 1
+	}
+	return c.costOf(key, value)
+}
+
+// Cost returns the total cost of the entries in the cache.
+// It's maintained on every change, so it's cheap enough for gauges.
+func (c *Cache) Cost() int64 {
+	return atomic.LoadInt64(&c.cost)
+}
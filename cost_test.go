@@ -0,0 +1,30 @@
+package cache
+
+import "testing"
+
+func TestCost(t *testing.T) {
+	ce := New(0, WithMaxCost(10), WithCost(func(_ Key, v interface{}) int64 {
+		return int64(len(v.(string)))
+	}))
+	ce.Set("a", "1234")
+	ce.Set("b", "1234")
+	if c := ce.Cost(); c != 8 {
+		t.Fatalf("Cost = %d, want 8", c)
+	}
+	ce.Set("b", "12")
+	if c := ce.Cost(); c != 6 {
+		t.Fatalf("Cost after update = %d, want 6", c)
+	}
+	ce.Set("c", "123456")
+	if ce.Has("a") || !ce.Has("b") || !ce.Has("c") {
+		t.Fatal("the oldest entry wasn't evicted over MaxCost")
+	}
+	if c := ce.Cost(); c != 8 {
+		t.Fatalf("Cost after eviction = %d, want 8", c)
+	}
+	ce.Remove("b")
+	ce.Clear()
+	if c := ce.Cost(); c != 0 {
+		t.Fatalf("Cost after Clear = %d, want 0", c)
+	}
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// counterShards is the number of cells of a counter. A counter takes
+// a cache line per cell, 512 bytes.
+const counterShards = 8
+
+// counter is a statistics counter split into cache-line padded cells,
+// so goroutines on different Ps mostly increment different cache
+// lines. The cells are summed when the counter is read.
+type counter struct {
+	cells [counterShards]struct {
+		n uint64
+		_ [56]byte
+	}
+}
+
+// add adds n to the cell of the calling P.
+func (c *counter) add(n uint64) {
+	atomic.AddUint64(&c.cells[shardHint()].n, n)
+}
+
+func (c *counter) load() uint64 {
+	var sum uint64
+	for i := range c.cells {
+		sum += atomic.LoadUint64(&c.cells[i].n)
+	}
+	return sum
+}
+
+// shardIndexes caches the cell indexes handed out round-robin by
+// nextShard. A sync.Pool keeps a private object per P, which Get
+// returns and Put gives back on the same P unless the goroutine was
+// rescheduled in between, so each P mostly sticks to one index. Go
+// doesn't expose the P a goroutine runs on otherwise.
+var (
+	nextShard    uint32
+	shardIndexes = sync.Pool{New: func() interface{} {
+		i := int(atomic.AddUint32(&nextShard, 1) % counterShards)
+		return &i
+	}}
+)
+
+// shardHint returns the cell index of the calling P.
+func shardHint() int {
+	p := shardIndexes.Get().(*int)
+	i := *p
+	shardIndexes.Put(p)
+	return i
+}
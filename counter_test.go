@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCounter(t *testing.T) {
+	var c counter
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := c.load(); n != 8000 {
+		t.Fatalf("load = %d, want 8000", n)
+	}
+}
+
+func BenchmarkCounter(b *testing.B) {
+	var c counter
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.add(1)
+		}
+	})
+}
+
+func TestShardHint(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		if h := shardHint(); h < 0 || h >= counterShards {
+			t.Fatalf("shardHint() = %d", h)
+		}
+	}
+	if n := testing.AllocsPerRun(100, func() { shardHint() }); n != 0 {
+		t.Fatalf("shardHint allocates %v times", n)
+	}
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"container/list"
+	"reflect"
+	"runtime"
+	"unsafe"
+)
+
+// DebugInfo describes the memory footprint of a Cache, to help
+// attribute GC pauses to the cache and tune its capacity.
+// Byte counts are estimates of the live heap held by each component.
+type DebugInfo struct {
+	// Entries is the number of entries in the cache.
+	Entries int
+	// Pointers is the estimated number of pointers the GC has to
+	// scan for the cache's own bookkeeping, excluding the values.
+	Pointers int
+
+	// StoreBytes is held by the key index, see Store.
+	StoreBytes int
+	// ListBytes is held by the recency list and the entries.
+	ListBytes int
+	// ValueBytes is the shallow size of the cached values.
+	ValueBytes int
+
+	// Mallocs and TotalAlloc are the heap objects and bytes allocated
+	// by the whole process since the cache was created.
+	Mallocs    uint64
+	TotalAlloc uint64
+	// NumGC is the number of GC cycles since the cache was created.
+	NumGC uint32
+}
+
+// byteSizer is implemented by stores which can estimate their size.
+type byteSizer interface {
+	bytes() int
+}
+
+// memBaseline records the allocation counters at cache creation.
+type memBaseline struct {
+	mallocs    uint64
+	totalAlloc uint64
+	numGC      uint32
+}
+
+func (b *memBaseline) read() {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	b.mallocs, b.totalAlloc, b.numGC = ms.Mallocs, ms.TotalAlloc, ms.NumGC
+}
+
+const (
+	elementSize = int(unsafe.Sizeof(list.Element{}))
+	entrySize   = int(unsafe.Sizeof(entry{}))
+	ifaceSize   = int(unsafe.Sizeof(Key(nil)))
+	ptrSize     = int(unsafe.Sizeof(uintptr(0)))
+)
+
+// Debug returns the memory footprint of the cache.
+// It stops the world to read the runtime's allocation statistics,
+// don't call it on a hot path.
+func (c *Cache) Debug() DebugInfo {
+	c.mu.Lock()
+	var info DebugInfo
+	if c.store != nil {
+		info.Entries = c.ll.Len()
+		if s, ok := c.store.(byteSizer); ok {
+			info.StoreBytes = s.bytes()
+		}
+		info.ListBytes = info.Entries * (elementSize + entrySize)
+		// list.Element: next, prev, list and the Value's data;
+		// entry: the key and value data; the store: the key data
+		// and the element.
+		info.Pointers = info.Entries * 8
+		for e := c.ll.Front(); e != nil; e = e.Next() {
+			info.ValueBytes += valueSize(e.Value.(*entry).value)
+		}
+	}
+	c.mu.Unlock()
+
+	var now memBaseline
+	now.read()
+	info.Mallocs = now.mallocs - c.baseline.mallocs
+	info.TotalAlloc = now.totalAlloc - c.baseline.totalAlloc
+	info.NumGC = now.numGC - c.baseline.numGC
+	return info
+}
+
+// valueSize estimates the shallow size of v.
+func valueSize(v interface{}) int {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return len(v)
+	case []byte:
+		return cap(v)
+	}
+	t := reflect.TypeOf(v)
+	switch t.Kind() {
+	case reflect.Ptr:
+		return int(t.Elem().Size())
+	case reflect.Slice:
+		return reflect.ValueOf(v).Cap() * int(t.Elem().Size())
+	}
+	return int(t.Size())
+}
+
+func (m mapStore) bytes() int {
+	// Go maps keep buckets at a load factor of 6.5 out of 8 slots,
+	// each slot holding a tophash byte, the key and the element.
+	return len(m) * (1 + ifaceSize + ptrSize) * 8 / 6
+}
+
+func (s *robinStore) bytes() int {
+	return len(s.slots)*int(unsafe.Sizeof(robinSlot{})) +
+		len(s.other)*(1+ifaceSize+ptrSize)*8/6
+}
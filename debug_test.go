@@ -0,0 +1,25 @@
+package cache
+
+import "testing"
+
+func TestDebug(t *testing.T) {
+	for _, newStore := range []func() Store{NewMapStore, NewRobinStore} {
+		ce := New(100, WithStore(newStore))
+		for i := 0; i < 10; i++ {
+			ce.Set(i, "0123456789")
+		}
+		info := ce.Debug()
+		if info.Entries != 10 {
+			t.Errorf("Entries = %d, want 10", info.Entries)
+		}
+		if info.ValueBytes != 100 {
+			t.Errorf("ValueBytes = %d, want 100", info.ValueBytes)
+		}
+		if info.StoreBytes == 0 || info.ListBytes == 0 || info.Pointers == 0 {
+			t.Errorf("missing estimates: %+v", info)
+		}
+		if info.Mallocs == 0 {
+			t.Errorf("Mallocs = 0 after inserting entries")
+		}
+	}
+}
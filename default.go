@@ -0,0 +1,47 @@
+package cache
+
+import "sync"
+
+// DefaultMaxEntries is the MaxEntries of the default cache created
+// when none was set with SetDefault.
+const DefaultMaxEntries = 10000
+
+// std is the default cache used by the package-level functions.
+var std struct {
+	mu sync.Mutex
+	c  *Cache
+}
+
+// Default returns the default cache, creating it with
+// DefaultMaxEntries on first use unless SetDefault was called.
+func Default() *Cache {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+	if std.c == nil {
+		std.c = New(DefaultMaxEntries)
+	}
+	return std.c
+}
+
+// SetDefault makes c the default cache used by the package-level
+// functions, for programs which need a configured one.
+func SetDefault(c *Cache) {
+	std.mu.Lock()
+	std.c = c
+	std.mu.Unlock()
+}
+
+// Set adds a value to the default cache, see Cache.Set.
+func Set(key Key, value interface{}) {
+	Default().Set(key, value)
+}
+
+// Get looks up a key's value from the default cache, see Cache.Get.
+func Get(key Key) (value interface{}, ok bool) {
+	return Default().Get(key)
+}
+
+// Remove removes key from the default cache.
+func Remove(key Key) {
+	Default().Remove(key)
+}
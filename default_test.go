@@ -0,0 +1,25 @@
+package cache
+
+import "testing"
+
+func TestDefault(t *testing.T) {
+	defer SetDefault(nil)
+	Set("a", 1)
+	if v, ok := Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v", v, ok)
+	}
+	if Default().MaxEntries != DefaultMaxEntries {
+		t.Fatalf("default MaxEntries = %d", Default().MaxEntries)
+	}
+	Remove("a")
+	if _, ok := Get("a"); ok {
+		t.Fatal("Get after Remove succeeded")
+	}
+
+	c := New(1)
+	SetDefault(c)
+	Set("b", 2)
+	if _, ok := c.Get("b"); !ok {
+		t.Fatal("Set didn't use the cache passed to SetDefault")
+	}
+}
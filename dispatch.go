@@ -0,0 +1,20 @@
+package cache
+
+// WithOrderedEvictions delivers OnEvicted and the callback set by
+// WithOnEvictedContext on a single dispatcher goroutine instead of
+// with the cache locked, for consumers replicating the changes of the
+// cache elsewhere. The callbacks are delivered strictly in eviction
+// order, one at a time, but only after the call which purged the
+// entries returned, so they may use the cache. Entries deferred by
+// Acquire are queued when their last reader releases them.
+// Callbacks waiting for the dispatcher count in Stats.Queued.
+// After Close, the dispatcher delivers the queued callbacks and exits;
+// later evictions call the callbacks synchronously again.
+func WithOrderedEvictions() Option {
+	return func(c *Cache) {
+		// The queue isn't bounded, no callback is dropped.
+		p := newWorkerPool(0)
+		go p.work()
+		c.dispatcher = p
+	}
+}
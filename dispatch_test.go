@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestOrderedEvictions(t *testing.T) {
+	c := New(0, WithMaxCost(3), WithOrderedEvictions())
+	defer c.Close()
+	var (
+		mu      sync.Mutex
+		evicted []Key
+		wg      sync.WaitGroup
+	)
+	c.OnEvicted = func(key Key, _ interface{}) {
+		// The cache isn't locked, the callback may use it.
+		c.Peek(key)
+		mu.Lock()
+		evicted = append(evicted, key)
+		mu.Unlock()
+		wg.Done()
+	}
+	wg.Add(7)
+	for i := 0; i < 10; i++ {
+		c.Set(i, i)
+	}
+	wg.Wait()
+	for i, key := range evicted {
+		if key != i {
+			t.Fatalf("evicted %v, want the keys in eviction order", evicted)
+		}
+	}
+}
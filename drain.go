@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrDraining is returned by the writes reporting errors, like TrySet,
+// once Drain has been called.
+var ErrDraining = errors.New("cache: draining")
+
+// DrainError reports the work Drain couldn't complete before its
+// context was done.
+type DrainError struct {
+	// InFlight lists the keys still being loaded.
+	InFlight []Key
+	// Queued is the number of asynchronous tasks, like expiry
+	// callbacks and refreshes, and of the eviction callbacks of
+	// WithOrderedEvictions, still queued or running.
+	Queued int
+	// Err is the error of the context.
+	Err error
+}
+
+func (e *DrainError) Error() string {
+	return fmt.Sprintf("cache: drain: %v with %d loads in flight and %d tasks queued", e.Err, len(e.InFlight), e.Queued)
+}
+
+func (e *DrainError) Unwrap() error {
+	return e.Err
+}
+
+// drainPoll is how often Drain checks for the pending work.
+const drainPoll = time.Millisecond
+
+// Drain prepares the cache for a graceful shutdown: it stops accepting
+// writes, which are dropped or fail with ErrDraining, while lookups are
+// still served. It then waits for the loads in flight, the asynchronous
+// tasks and eviction callbacks, queued or running, to complete, or returns a
+// *DrainError listing what's left when ctx is done. Loads completing
+// while the cache drains return their values without caching them.
+func (c *Cache) Drain(ctx context.Context) error {
+	atomic.StoreInt32(&c.draining, 1)
+	ticker := time.NewTicker(drainPoll)
+	defer ticker.Stop()
+	for {
+		inFlight, queued := c.InFlight(), c.pendingTasks()
+		if len(inFlight) == 0 && queued == 0 {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return &DrainError{InFlight: inFlight, Queued: queued, Err: ctx.Err()}
+		}
+	}
+}
+
+func (c *Cache) isDraining() bool {
+	return atomic.LoadInt32(&c.draining) != 0
+}
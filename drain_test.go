@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	c := New(10)
+	c.Set("a", 1)
+	release := make(chan struct{})
+	go c.GetOrLoad("slow", func(Key) (interface{}, error) {
+		<-release
+		return 2, nil
+	})
+	for len(c.InFlight()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := c.Drain(ctx)
+	var derr *DrainError
+	if !errors.As(err, &derr) || len(derr.InFlight) != 1 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Drain error = %v", err)
+	}
+
+	close(release)
+	if err := c.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain error = %v after the load completed", err)
+	}
+	if _, ok := c.Peek("slow"); ok {
+		t.Fatal("a load completing while draining was cached")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatal("Get isn't served while draining")
+	}
+	c.Set("b", 1)
+	c.SetAll(map[Key]interface{}{"c": 1})
+	if c.Len() != 1 {
+		t.Fatalf("Len() = %d, writes were accepted while draining", c.Len())
+	}
+	if err := c.TrySet("b", 1); err != ErrDraining {
+		t.Fatalf("TrySet error = %v, want ErrDraining", err)
+	}
+}
+
+func TestDrainRunningTasks(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithWorkers(1)}} {
+		c := New(10, opts...)
+		release, finished := make(chan struct{}), make(chan struct{})
+		c.SetWithExpire("a", 1, time.Millisecond)
+		c.OnExpireSchedule("a", func(Key, interface{}) {
+			<-release
+			close(finished)
+		})
+		time.Sleep(5 * time.Millisecond)
+		c.RemoveExpire()
+		// The callback was dequeued and is running.
+		time.Sleep(5 * time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		var derr *DrainError
+		if err := c.Drain(ctx); !errors.As(err, &derr) || derr.Queued != 1 {
+			t.Fatalf("Drain error = %v with a callback running", err)
+		}
+		cancel()
+		close(release)
+		if err := c.Drain(context.Background()); err != nil {
+			t.Fatalf("Drain error = %v", err)
+		}
+		select {
+		case <-finished:
+		default:
+			t.Fatal("Drain returned before the callback finished")
+		}
+		c.Close()
+	}
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// DumpCSV writes one CSV record per entry to w, from the most to the
+// least recently used, for offline capacity analysis. The columns are
+//
+//	key, value, size, hits, age, ttl
+//
+// where size is the estimated size of the value in bytes, hits the
+// number of lookups which returned it, age the seconds since it was
+// set and ttl the seconds until it expires, empty if it doesn't.
+// Values are rendered by valueFormatter, or fmt.Sprint if it's nil,
+// and keys are redacted as set by WithKeyRedactor.
+func (c *Cache) DumpCSV(w io.Writer, valueFormatter func(interface{}) string) error {
+	return c.dump(w, ',', valueFormatter)
+}
+
+// DumpTSV is like DumpCSV, separating the columns by tabs.
+func (c *Cache) DumpTSV(w io.Writer, valueFormatter func(interface{}) string) error {
+	return c.dump(w, '\t', valueFormatter)
+}
+
+type dumpRow struct {
+	key     Key
+	value   interface{}
+	hits    uint64
+	created int64
+	expire  int64
+}
+
+func (c *Cache) dump(w io.Writer, comma rune, valueFormatter func(interface{}) string) error {
+	if valueFormatter == nil {
+		valueFormatter = func(v interface{}) string { return fmt.Sprint(v) }
+	}
+	// Copy the entries so the cache isn't locked while w is written.
+	c.mu.Lock()
+	var rows []dumpRow
+	if c.store != nil {
+		rows = make([]dumpRow, 0, c.ll.Len())
+		for el := c.ll.Front(); el != nil; el = el.Next() {
+			e := el.Value.(*entry)
+			rows = append(rows, dumpRow{e.key, e.value, e.hits, e.created, e.expire})
+		}
+	}
+	c.mu.Unlock()
+
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if err := cw.Write([]string{"key", "value", "size", "hits", "age", "ttl"}); err != nil {
+		return err
+	}
+	now := c.now()
+	for _, r := range rows {
+		ttl := ""
+		if r.expire > 0 {
+			ttl = strconv.FormatFloat(time.Duration(r.expire-now).Seconds(), 'f', 3, 64)
+		}
+		age := time.Duration(now - r.created).Seconds()
+		err := cw.Write([]string{
+			c.keyString(r.key),
+			valueFormatter(r.value),
+			strconv.Itoa(valueSize(r.value)),
+			strconv.FormatUint(r.hits, 10),
+			strconv.FormatFloat(age, 'f', 3, 64),
+			ttl,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDumpCSV(t *testing.T) {
+	ce := New(10)
+	ce.Set("a", "hello")
+	ce.SetWithExpire("b", 42, time.Hour)
+	ce.Get("a")
+	ce.Get("a")
+
+	var sb strings.Builder
+	if err := ce.DumpCSV(&sb, nil); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(sb.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want header and 2 entries", len(records))
+	}
+	a := records[1]
+	if a[0] != "a" || a[1] != "hello" || a[2] != "5" || a[3] != "2" || a[5] != "" {
+		t.Errorf("record of a = %q", a)
+	}
+	if b := records[2]; b[0] != "b" || b[5] == "" {
+		t.Errorf("record of b = %q", b)
+	}
+
+	sb.Reset()
+	if err := ce.DumpTSV(&sb, func(interface{}) string { return "x" }); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sb.String(), "key\tvalue\t") {
+		t.Errorf("TSV header = %q", strings.SplitN(sb.String(), "\n", 2)[0])
+	}
+}
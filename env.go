@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// NewFromEnv creates a Cache configured by the environment variables
+// named prefix followed by _MAX_ENTRIES, _MAX_COST, _DEFAULT_TTL,
+// _MIN_TTL, _MAX_TTL and _JANITOR_INTERVAL, e.g. LRUCACHE_MAX_ENTRIES
+// for the prefix LRUCACHE. Durations are parsed by time.ParseDuration.
+// Unset variables leave the parameters zero, see Config.
+func NewFromEnv(prefix string, opts ...Option) (*Cache, error) {
+	cfg, err := configFromEnv(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromConfig(cfg, opts...)
+}
+
+func configFromEnv(prefix string) (Config, error) {
+	var cfg Config
+	var maxEntries int64
+	for _, v := range []struct {
+		name string
+		n    *int64
+		d    *time.Duration
+	}{
+		{name: "MAX_ENTRIES", n: &maxEntries},
+		{name: "MAX_COST", n: &cfg.MaxCost},
+		{name: "DEFAULT_TTL", d: &cfg.DefaultTTL},
+		{name: "MIN_TTL", d: &cfg.MinTTL},
+		{name: "MAX_TTL", d: &cfg.MaxTTL},
+		{name: "JANITOR_INTERVAL", d: &cfg.JanitorInterval},
+	} {
+		name := prefix + "_" + v.name
+		s, ok := os.LookupEnv(name)
+		if !ok || s == "" {
+			continue
+		}
+		var err error
+		if v.n != nil {
+			*v.n, err = strconv.ParseInt(s, 10, 0)
+		} else {
+			*v.d, err = time.ParseDuration(s)
+		}
+		if err != nil {
+			return Config{}, fmt.Errorf("cache: %s: %v", name, err)
+		}
+	}
+	cfg.MaxEntries = int(maxEntries)
+	return cfg, nil
+}
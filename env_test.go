@@ -0,0 +1,29 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewFromEnv(t *testing.T) {
+	t.Setenv("TESTCACHE_MAX_ENTRIES", "100")
+	t.Setenv("TESTCACHE_DEFAULT_TTL", "5m")
+	t.Setenv("TESTCACHE_MAX_TTL", "1h")
+	c, err := NewFromEnv("TESTCACHE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{MaxEntries: 100, DefaultTTL: 5 * time.Minute, MaxTTL: time.Hour}
+	if got := c.Config(); got != want {
+		t.Fatalf("Config() = %+v, want %+v", got, want)
+	}
+
+	t.Setenv("TESTCACHE_MIN_TTL", "soon")
+	if _, err := NewFromEnv("TESTCACHE"); err == nil {
+		t.Fatal("NewFromEnv accepted an invalid duration")
+	}
+	t.Setenv("TESTCACHE_MIN_TTL", "2h")
+	if _, err := NewFromEnv("TESTCACHE"); err == nil {
+		t.Fatal("NewFromEnv accepted MinTTL above MaxTTL")
+	}
+}
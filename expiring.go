@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"sort"
+	"time"
+)
+
+// Entry is a snapshot of a cache entry.
+type Entry struct {
+	Key   Key
+	Value interface{}
+	// Expire is when the entry expires, zero if it doesn't.
+	Expire time.Time
+}
+
+// ExpiringWithin returns the entries which expire within d, soonest
+// first, so refresh-ahead schedulers and dashboards can see what's
+// about to fall out. Entries which have expired already are included.
+func (c *Cache) ExpiringWithin(d time.Duration) []Entry {
+	c.mu.Lock()
+	if c.store == nil {
+		c.mu.Unlock()
+		return nil
+	}
+	deadline := c.now() + int64(d)
+	var found []*entry
+	c.timers.scan(deadline, func(e *entry) {
+		if e.expire <= deadline {
+			found = append(found, e)
+		}
+	})
+	entries := make([]Entry, len(found))
+	for i, e := range found {
+		entries[i] = Entry{Key: e.key, Value: e.value, Expire: wallTime(e.expire)}
+	}
+	c.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Expire.Before(entries[j].Expire)
+	})
+	return entries
+}
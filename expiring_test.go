@@ -0,0 +1,28 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiringWithin(t *testing.T) {
+	ce := New(10)
+	ce.SetWithExpire("later", 1, 3*time.Second)
+	ce.SetWithExpire("soon", 2, time.Second)
+	ce.SetWithExpire("never", 3, time.Hour)
+	ce.Set("forever", 4)
+
+	got := ce.ExpiringWithin(5 * time.Second)
+	if len(got) != 2 || got[0].Key != "soon" || got[1].Key != "later" {
+		t.Fatalf("ExpiringWithin(5s) = %v, want [soon later]", got)
+	}
+	if got[0].Value != 2 || got[0].Expire.IsZero() {
+		t.Fatalf("entry = %+v", got[0])
+	}
+	if got := ce.ExpiringWithin(time.Millisecond); len(got) != 0 {
+		t.Fatalf("ExpiringWithin(1ms) = %v, want none", got)
+	}
+	if got := ce.ExpiringWithin(1000 * time.Hour); len(got) != 3 {
+		t.Fatalf("ExpiringWithin(1000h) = %v, want 3 entries", got)
+	}
+}
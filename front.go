@@ -0,0 +1,27 @@
+package cache
+
+import "container/list"
+
+// WithFrontElision skips moving an accessed entry to the front of the
+// recency list when it's still among the k most recently moved ones,
+// saving the pointer writes for skewed workloads where the same few
+// keys are hit constantly. The eviction order only changes among the
+// k most recent entries, which are the last to be evicted anyway.
+func WithFrontElision(k int) Option {
+	return func(c *Cache) {
+		c.frontElision = uint64(k)
+	}
+}
+
+// touch moves ele to the front of the list. The caller must hold c.mu.
+func (c *Cache) touch(ele *list.Element) {
+	e := ele.Value.(*entry)
+	// Every move puts one element in front of e, so e is at most
+	// c.gen-e.gen positions from the front.
+	if c.gen-e.gen < c.frontElision {
+		return
+	}
+	c.ll.MoveToFront(ele)
+	c.gen++
+	e.gen = c.gen
+}
@@ -0,0 +1,33 @@
+package cache
+
+import "testing"
+
+func TestFrontElision(t *testing.T) {
+	ce := New(0, WithFrontElision(2))
+	ce.Set("a", 1)
+	ce.Set("b", 2)
+	ce.Set("c", 3)
+	// a is 2 moves behind, b is within the front 2.
+	ce.Get("b")
+	ce.Get("a")
+	var order []Key
+	for e := ce.ll.Front(); e != nil; e = e.Next() {
+		order = append(order, e.Value.(*entry).key)
+	}
+	if len(order) != 3 || order[0] != "a" || order[1] != "c" || order[2] != "b" {
+		t.Fatalf("order = %v, want [a c b]", order)
+	}
+}
+
+func BenchmarkGetFrontElision(b *testing.B) {
+	ce := New(0, WithFrontElision(8))
+	keys := []Key{0, 1, 2, 3}
+	for _, k := range keys {
+		ce.Set(k, k)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ce.Get(keys[i&3])
+	}
+}
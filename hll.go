@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"fmt"
+	"hash/maphash"
+	"math"
+	"math/bits"
+	"sync/atomic"
+)
+
+// hllPrecision is the number of hash bits indexing the registers of
+// the HyperLogLog, for a standard error of 1.04/sqrt(2^12), about 1.6%.
+const (
+	hllPrecision = 12
+	hllRegisters = 1 << hllPrecision
+)
+
+// hyperLogLog estimates the number of distinct keys it's shown.
+// Its registers are updated atomically, without holding c.mu.
+type hyperLogLog struct {
+	seed      maphash.Seed
+	registers [hllRegisters]uint32
+}
+
+// WithKeyCardinality estimates the number of distinct keys looked up
+// with Get or written since the cache was created, resident or not,
+// in Stats.DistinctKeys. Comparing it to MaxEntries tells whether poor
+// hit ratios come from a working set larger than the cache. The
+// estimate takes 16KiB and is within a few percent; hashing keys other
+// than strings and integers allocates.
+func WithKeyCardinality() Option {
+	return func(c *Cache) {
+		c.cardinality = &hyperLogLog{seed: maphash.MakeSeed()}
+	}
+}
+
+// add records key.
+func (h *hyperLogLog) add(key Key) {
+	x := h.hash(key)
+	r := &h.registers[x>>(64-hllPrecision)]
+	rank := uint32(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	for {
+		old := atomic.LoadUint32(r)
+		if rank <= old || atomic.CompareAndSwapUint32(r, old, rank) {
+			return
+		}
+	}
+}
+
+func (h *hyperLogLog) hash(key Key) uint64 {
+	switch k := key.(type) {
+	case int:
+		return mix64(uint64(k))
+	case int64:
+		return mix64(uint64(k))
+	case int32:
+		return mix64(uint64(k))
+	case uint:
+		return mix64(uint64(k))
+	case uint64:
+		return mix64(k)
+	case uint32:
+		return mix64(uint64(k))
+	}
+	var mh maphash.Hash
+	mh.SetSeed(h.seed)
+	if s, ok := key.(string); ok {
+		mh.WriteString(s)
+	} else {
+		fmt.Fprintf(&mh, "%T:%v", key, key)
+	}
+	return mh.Sum64()
+}
+
+// estimate returns the estimated number of distinct keys, 0 for a nil h.
+func (h *hyperLogLog) estimate() uint64 {
+	if h == nil {
+		return 0
+	}
+	const m = float64(hllRegisters)
+	sum, zeros := 0.0, 0
+	for i := range h.registers {
+		r := atomic.LoadUint32(&h.registers[i])
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	e := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		// Linear counting is more accurate for small cardinalities.
+		e = m * math.Log(m/float64(zeros))
+	}
+	return uint64(e + 0.5)
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestKeyCardinality(t *testing.T) {
+	c := New(100, WithKeyCardinality())
+	for _, n := range []int{100, 10000, 100000} {
+		for i := 0; i < n; i++ {
+			if i%2 == 0 {
+				c.Set(strconv.Itoa(i), i)
+			} else {
+				c.Get(strconv.Itoa(i))
+			}
+			// Repeated keys don't count.
+			c.Get(strconv.Itoa(i / 2))
+		}
+		// The standard error is 1.6%, the hash seed is random.
+		got := float64(c.Stats().DistinctKeys)
+		if got < 0.9*float64(n) || got > 1.1*float64(n) {
+			t.Errorf("DistinctKeys = %v after %d distinct keys", got, n)
+		}
+	}
+	if New(10).Stats().DistinctKeys != 0 {
+		t.Fatal("DistinctKeys isn't 0 without WithKeyCardinality")
+	}
+}
+
+func TestKeyCardinalityTypes(t *testing.T) {
+	type point struct{ x, y int }
+	c := New(10, WithKeyCardinality())
+	for i := 0; i < 1000; i++ {
+		c.Get(i)
+		c.Get(point{i, i})
+	}
+	if got := c.Stats().DistinctKeys; got < 1900 || got > 2100 {
+		t.Fatalf("DistinctKeys = %d after 2000 distinct keys", got)
+	}
+}
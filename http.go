@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// cachedResponse is an HTTP response stored by Middleware.
+type cachedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// Middleware returns an HTTP middleware caching the responses of the
+// wrapped handler in c for ttl, and serving later requests with the
+// same key directly from the cache.
+//
+// keyFn returns the cache key of a request, a nil key bypasses the
+// cache. If keyFn is nil, GET and HEAD requests are keyed by their
+// method and URL, and requests with an Authorization or a Cookie
+// header bypass the cache, since their responses may be personalized.
+// Only 200 responses without Set-Cookie and without a no-store or
+// private Cache-Control are cached. Responses with a Vary header are
+// cached per value of the request headers it lists, except for
+// "Vary: *" which isn't cached.
+func Middleware(c *Cache, keyFn func(*http.Request) Key, ttl time.Duration) func(http.Handler) http.Handler {
+	if keyFn == nil {
+		keyFn = requestKey
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := keyFn(r)
+			if key == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if v, ok := c.Get(key); ok {
+				if vary, ok := v.(*varyHeaders); ok {
+					v, ok = c.Get(vary.key(key, r))
+				}
+				if resp, ok := v.(*cachedResponse); ok {
+					resp.serve(w)
+					return
+				}
+			}
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			if !cacheable(rec) {
+				return
+			}
+			resp := &cachedResponse{
+				status: rec.status,
+				header: rec.Header().Clone(),
+				body:   rec.body.Bytes(),
+			}
+			if names := rec.Header().Values("Vary"); len(names) > 0 {
+				vary := newVaryHeaders(names)
+				c.SetWithExpire(key, vary, ttl)
+				c.SetWithExpire(vary.key(key, r), resp, ttl)
+				return
+			}
+			c.SetWithExpire(key, resp, ttl)
+		})
+	}
+}
+
+func requestKey(r *http.Request) Key {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return nil
+	}
+	if r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+		return nil
+	}
+	return r.Method + " " + r.URL.String()
+}
+
+func cacheable(rec *responseRecorder) bool {
+	if rec.status != http.StatusOK || rec.Header().Get("Set-Cookie") != "" {
+		return false
+	}
+	for _, name := range newVaryHeaders(rec.Header().Values("Vary")).names {
+		if name == "*" {
+			return false
+		}
+	}
+	cc := strings.ToLower(rec.Header().Get("Cache-Control"))
+	return !strings.Contains(cc, "no-store") && !strings.Contains(cc, "private")
+}
+
+// varyHeaders is stored under the key of a request whose response
+// varies with the request headers it lists, see Middleware.
+type varyHeaders struct {
+	names []string
+}
+
+func newVaryHeaders(values []string) *varyHeaders {
+	vary := &varyHeaders{}
+	for _, v := range values {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				vary.names = append(vary.names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return vary
+}
+
+// varyKey is the key of a response varying with request headers.
+type varyKey struct {
+	key    Key
+	values string
+}
+
+// key returns the key of the response to r under key.
+func (vary *varyHeaders) key(key Key, r *http.Request) Key {
+	var b strings.Builder
+	for _, name := range vary.names {
+		b.WriteString(name)
+		b.WriteString(": ")
+		b.WriteString(strings.Join(r.Header.Values(name), ", "))
+		b.WriteByte('\n')
+	}
+	return varyKey{key, b.String()}
+}
+
+func (resp *cachedResponse) serve(w http.ResponseWriter) {
+	h := w.Header()
+	for k, v := range resp.header {
+		// The cached slices are shared by every request.
+		h[k] = append([]string(nil), v...)
+	}
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+}
+
+// responseRecorder passes a response through while recording it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
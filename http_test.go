@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	calls := 0
+	h := Middleware(New(10), nil, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/private" {
+			w.Header().Set("Cache-Control", "private")
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintf(w, "call %d", calls)
+	}))
+
+	get := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+	for i := 0; i < 3; i++ {
+		w := get("GET", "/a")
+		if w.Body.String() != "call 1" || w.Header().Get("Content-Type") != "text/plain" {
+			t.Fatalf("GET /a = %q, %v", w.Body.String(), w.Header())
+		}
+	}
+	if w := get("POST", "/a"); w.Body.String() != "call 2" {
+		t.Fatalf("POST was served from the cache: %q", w.Body.String())
+	}
+	get("GET", "/private")
+	if w := get("GET", "/private"); w.Body.String() != "call 4" {
+		t.Fatalf("private response was cached: %q", w.Body.String())
+	}
+}
+
+func TestMiddlewarePersonalized(t *testing.T) {
+	calls := 0
+	h := Middleware(New(10), nil, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/vary" {
+			w.Header().Set("Vary", "Accept-Language")
+		}
+		if r.URL.Path == "/any" {
+			w.Header().Set("Vary", "*")
+		}
+		fmt.Fprintf(w, "call %d", calls)
+	}))
+	get := func(path string, header ...string) string {
+		r := httptest.NewRequest("GET", path, nil)
+		for i := 0; i < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Body.String()
+	}
+
+	get("/me", "Authorization", "Bearer a")
+	if body := get("/me", "Authorization", "Bearer b"); body != "call 2" {
+		t.Fatalf("authorized response was cached: %q", body)
+	}
+	get("/me", "Cookie", "session=a")
+	if body := get("/me", "Cookie", "session=b"); body != "call 4" {
+		t.Fatalf("response to a request with cookies was cached: %q", body)
+	}
+
+	get("/vary", "Accept-Language", "en")
+	get("/vary", "Accept-Language", "fr")
+	if body := get("/vary", "Accept-Language", "en"); body != "call 5" {
+		t.Fatalf("GET /vary in English = %q, want call 5", body)
+	}
+	if body := get("/vary", "Accept-Language", "fr"); body != "call 6" {
+		t.Fatalf("GET /vary in French = %q, want call 6", body)
+	}
+	get("/any")
+	if body := get("/any"); body != "call 8" {
+		t.Fatalf("Vary: * response was cached: %q", body)
+	}
+}
+
+func TestMiddlewareHeaderCopy(t *testing.T) {
+	h := Middleware(New(10), nil, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "cached")
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	w.Header()["X-Test"][0] = "mutated"
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if v := w.Header().Get("X-Test"); v != "cached" {
+		t.Fatalf("X-Test = %q, a served response modified the cached one", v)
+	}
+}
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithMaxIdle removes the entries which haven't been returned or set
+// for d, whatever their TTL and however much room is left, reclaiming
+// the memory of abandoned keys in long-running processes. Idle entries
+// are removed when expired ones are, by the janitor, see WithJanitor,
+// or RemoveExpire, and are counted as evictions. The scan walks from
+// the least recently used entry and stops at the first one used within
+// d, so it only visits the entries it removes.
+func WithMaxIdle(d time.Duration) Option {
+	return func(c *Cache) {
+		c.maxIdle = int64(d)
+	}
+}
+
+// removeIdle removes the entries idle for longer than the maximum.
+// The caller must hold c.mu.
+func (c *Cache) removeIdle(now int64) {
+	if c.maxIdle <= 0 {
+		return
+	}
+	for ele := c.ll.Back(); ele != nil; ele = c.ll.Back() {
+		e := ele.Value.(*entry)
+		if read := atomic.LoadInt64(&e.read); read > e.accessed {
+			// Returned from a read snapshot since, see WithReadSnapshots.
+			e.accessed = read
+			c.ll.MoveToFront(ele)
+			c.gen++
+			e.gen = c.gen
+			continue
+		}
+		if now-e.accessed < c.maxIdle {
+			return
+		}
+		c.stats.evictions++
+		c.removeElement(ele)
+	}
+}
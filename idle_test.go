@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaxIdle(t *testing.T) {
+	c := New(10, WithMaxIdle(20*time.Millisecond))
+	c.Set("a", 1)
+	c.SetWithExpire("b", 1, time.Hour)
+	c.Set("c", 1)
+	for i := 0; i < 5; i++ {
+		time.Sleep(5 * time.Millisecond)
+		c.Get("a")
+	}
+	c.RemoveExpire()
+	if _, ok := c.Peek("a"); !ok {
+		t.Fatal("a used entry was removed as idle")
+	}
+	if _, ok := c.Peek("b"); ok {
+		t.Fatal("an idle entry outlived the max idle time")
+	}
+	if _, ok := c.Peek("c"); ok {
+		t.Fatal("an idle entry without TTL outlived the max idle time")
+	}
+	if s := c.Stats(); s.Evictions != 2 {
+		t.Fatalf("Stats().Evictions = %d, want 2", s.Evictions)
+	}
+}
+
+func TestMaxIdleSetEqual(t *testing.T) {
+	c := New(10, WithMaxIdle(20*time.Millisecond), WithValueEqual(func(a, b interface{}) bool { return a == b }))
+	c.Set("a", 1)
+	for i := 0; i < 5; i++ {
+		time.Sleep(5 * time.Millisecond)
+		c.Set("a", 1)
+	}
+	c.RemoveExpire()
+	if _, ok := c.Peek("a"); !ok {
+		t.Fatal("an entry set to an equal value was removed as idle")
+	}
+}
+
+func TestMaxIdleReadSnapshots(t *testing.T) {
+	c := New(10, WithMaxIdle(20*time.Millisecond), WithReadSnapshots(time.Millisecond))
+	defer c.Close()
+	c.Set("a", 1)
+	c.Set("b", 1)
+	waitSnapshot(t, c)
+	for i := 0; i < 5; i++ {
+		time.Sleep(5 * time.Millisecond)
+		c.Get("a")
+	}
+	c.RemoveExpire()
+	if _, ok := c.Peek("a"); !ok {
+		t.Fatal("an entry read from the snapshot was removed as idle")
+	}
+	if _, ok := c.Peek("b"); ok {
+		t.Fatal("an idle entry outlived the max idle time")
+	}
+}
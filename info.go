@@ -0,0 +1,46 @@
+package cache
+
+import "time"
+
+// EntryInfo describes a cache entry.
+type EntryInfo struct {
+	Key Key
+	// Hits counts the lookups which returned the value.
+	Hits uint64
+	// Created is when the value was set and Accessed when it was last
+	// returned or set. Expire is when it expires, zero if it doesn't.
+	Created  time.Time
+	Accessed time.Time
+	Expire   time.Time
+	Cost     int64
+	// SetBy lists the innermost call sites of the write which last set
+	// the entry, as "function file:line", see WithProvenance.
+	SetBy []string
+}
+
+// Info describes the entry of key. It isn't a lookup: it doesn't
+// count as a hit nor make the entry recently used.
+func (c *Cache) Info(key Key) (EntryInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil {
+		return EntryInfo{}, false
+	}
+	ele, ok := c.store.Get(key)
+	if !ok || ele.Value.(*entry).expired(c.now()) {
+		return EntryInfo{}, false
+	}
+	e := ele.Value.(*entry)
+	info := EntryInfo{
+		Key:      e.key,
+		Hits:     e.hits,
+		Created:  wallTime(e.created),
+		Accessed: wallTime(e.accessed),
+		Cost:     e.cost,
+		SetBy:    e.setBy.sites(),
+	}
+	if e.expire > 0 {
+		info.Expire = wallTime(e.expire)
+	}
+	return info, true
+}
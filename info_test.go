@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInfo(t *testing.T) {
+	c := New(10)
+	c.SetWithExpire("a", 1, time.Hour)
+	c.Get("a")
+	c.Get("a")
+	info, ok := c.Info("a")
+	if !ok || info.Key != "a" || info.Hits != 2 || info.Cost != 1 {
+		t.Fatalf("Info(a) = %+v, %v", info, ok)
+	}
+	if d := time.Until(info.Expire); d <= 59*time.Minute || d > time.Hour {
+		t.Fatalf("Info(a).Expire in %v", d)
+	}
+	if info.Accessed.Before(info.Created) {
+		t.Fatalf("Info(a) accessed before created: %+v", info)
+	}
+	if _, ok := c.Info("b"); ok {
+		t.Fatal("Info of a missing key succeeded")
+	}
+}
+
+func TestMaxHits(t *testing.T) {
+	c := New(10, WithMaxHits(2))
+	c.Set("a", 1)
+	for i := 0; i < 2; i++ {
+		if _, ok := c.Get("a"); !ok {
+			t.Fatalf("hit %d missed", i+1)
+		}
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("entry served past its max hits")
+	}
+	c.Set("a", 2)
+	if v, ok := c.Get("a"); !ok || v != 2 {
+		t.Fatal("a new value wasn't served")
+	}
+	if info, _ := c.Info("a"); info.Hits != 1 {
+		t.Fatalf("Info(a).Hits = %d", info.Hits)
+	}
+}
+
+func setFromHelper(c *Cache) {
+	c.Set("a", 2)
+}
+
+func TestProvenance(t *testing.T) {
+	c := New(10, WithProvenance())
+	c.Set("a", 1)
+	setFromHelper(c)
+	info, _ := c.Info("a")
+	if len(info.SetBy) == 0 || !strings.Contains(info.SetBy[0], ".setFromHelper ") || !strings.Contains(info.SetBy[0], "info_test.go:") {
+		t.Fatalf("Info(a).SetBy = %q", info.SetBy)
+	}
+	c = New(10)
+	c.Set("a", 1)
+	if info, _ := c.Info("a"); info.SetBy != nil {
+		t.Fatal("SetBy recorded without WithProvenance")
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !cachedebug
+// +build !cachedebug
+
+package cache
+
+// checkInvariants is a no-op without the cachedebug build tag,
+// see invariants_debug.go.
+func (c *Cache) checkInvariants() {}
@@ -0,0 +1,80 @@
+//go:build cachedebug
+// +build cachedebug
+
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// checkInvariants panics with a dump of the cache if its structures
+// are inconsistent. It's compiled in by the cachedebug build tag, so
+// integration tests catch corruption at the mutation which caused it:
+//
+//	go test -tags cachedebug ./...
+//
+// It's O(n), and called after every mutation. The caller must hold c.mu.
+func (c *Cache) checkInvariants() {
+	if c.store == nil {
+		if n := atomic.LoadInt64(&c.size); n != 0 {
+			c.corrupt(fmt.Sprintf("size %d without a store", n))
+		}
+		return
+	}
+	if n, m, size := c.ll.Len(), c.store.Len(), atomic.LoadInt64(&c.size); n != m || int64(n) != size {
+		c.corrupt(fmt.Sprintf("%d list entries, %d store entries, size %d", n, m, size))
+	}
+	// Without a janitor, expired entries are only removed lazily.
+	lag := int64(0)
+	if c.janitorInterval > 0 {
+		lag = 10*int64(c.janitorInterval) + int64(time.Second)
+	}
+	now := c.now()
+	var cost int64
+	for ele := c.ll.Front(); ele != nil; ele = ele.Next() {
+		e := ele.Value.(*entry)
+		cost += e.cost
+		if got, ok := c.store.Get(e.key); !ok || got != ele {
+			c.corrupt(fmt.Sprintf("key %s isn't indexed by the store", c.keyString(e.key)))
+		}
+		if e.removed {
+			c.corrupt(fmt.Sprintf("key %s was removed", c.keyString(e.key)))
+		}
+		if c.byCost != nil && (e.costIdx >= len(c.byCost.entries) || c.byCost.entries[e.costIdx] != e) {
+			c.corrupt(fmt.Sprintf("key %s isn't indexed by cost", c.keyString(e.key)))
+		}
+		if e.expire > 0 && e.timer == nil {
+			c.corrupt(fmt.Sprintf("key %s isn't scheduled to expire", c.keyString(e.key)))
+		}
+		if lag > 0 && e.expire > 0 && now-e.expire > lag {
+			c.corrupt(fmt.Sprintf("key %s expired %v ago", c.keyString(e.key), time.Duration(now-e.expire)))
+		}
+	}
+	if c.byCost != nil && len(c.byCost.entries) != c.ll.Len() {
+		c.corrupt(fmt.Sprintf("%d list entries, %d indexed by cost", c.ll.Len(), len(c.byCost.entries)))
+	}
+	if total := atomic.LoadInt64(&c.cost); total != cost {
+		c.corrupt(fmt.Sprintf("cost %d, entries cost %d", total, cost))
+	}
+}
+
+// corrupt panics with problem and a dump of the first entries.
+func (c *Cache) corrupt(problem string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "cache: corrupted: %s\n", problem)
+	if c.ll != nil {
+		i := 0
+		for ele := c.ll.Front(); ele != nil && i < 20; ele = ele.Next() {
+			e := ele.Value.(*entry)
+			fmt.Fprintf(&b, "\tkey=%s cost=%d expire=%d hits=%d\n", c.keyString(e.key), e.cost, e.expire, e.hits)
+			i++
+		}
+		if c.ll.Len() > i {
+			fmt.Fprintf(&b, "\t... %d more\n", c.ll.Len()-i)
+		}
+	}
+	panic(b.String())
+}
@@ -0,0 +1,29 @@
+//go:build cachedebug
+// +build cachedebug
+
+package cache
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckInvariants(t *testing.T) {
+	c := New(10)
+	for i := 0; i < 20; i++ {
+		c.Set(i, i)
+	}
+	c.Remove(15)
+	c.Reset()
+	c.Set("a", 1)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store.Delete("a")
+	defer func() {
+		if msg, _ := recover().(string); !strings.Contains(msg, "corrupted") {
+			t.Fatalf("corruption wasn't caught: %v", msg)
+		}
+	}()
+	c.checkInvariants()
+}
@@ -0,0 +1,79 @@
+package cache
+
+import "sort"
+
+// Cursor is a position in the enumeration of KeysPage.
+// The zero Cursor starts a new enumeration.
+type Cursor struct {
+	// after is the seq of the last key returned and until the last
+	// seq when the enumeration started.
+	after, until uint64
+}
+
+// KeysPage returns up to limit keys following cursor, and the cursor of
+// the next page, which is zero once the enumeration is complete.
+// Keys are enumerated in the order they were added: an enumeration
+// covers the keys in the cache when it started, skipping the ones
+// removed since, so admin UIs can browse large caches page by page.
+// The keys are indexed in that order, so a page takes O(log n + limit)
+// time, plus the removed entries the index hasn't dropped yet.
+func (c *Cache) KeysPage(cursor Cursor, limit int) ([]Key, Cursor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil || limit <= 0 {
+		return nil, Cursor{}
+	}
+	if cursor.until == 0 {
+		cursor.until = c.seq
+	}
+	now := c.now()
+	entries := c.bySeq.entries
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].seq > cursor.after })
+	var keys []Key
+	for ; i < len(entries) && entries[i].seq <= cursor.until; i++ {
+		e := entries[i]
+		if e.removed || e.expired(now) {
+			continue
+		}
+		if len(keys) == limit {
+			return keys, cursor
+		}
+		keys = append(keys, e.key)
+		cursor.after = e.seq
+	}
+	return keys, Cursor{}
+}
+
+// seqIndex lists the entries in the order they were added, see
+// KeysPage. Removed entries are dropped once they're half of it.
+type seqIndex struct {
+	entries []*entry
+	dead    int
+}
+
+// add appends e, which has the highest seq so far.
+func (x *seqIndex) add(e *entry) {
+	x.entries = append(x.entries, e)
+}
+
+// removed records the removal of an entry.
+func (x *seqIndex) removed() {
+	x.dead++
+	if x.dead > len(x.entries)/2 {
+		live := x.entries[:0]
+		for _, e := range x.entries {
+			if !e.removed {
+				live = append(live, e)
+			}
+		}
+		for i := len(live); i < len(x.entries); i++ {
+			x.entries[i] = nil
+		}
+		x.entries, x.dead = live, 0
+	}
+}
+
+// reset drops every entry.
+func (x *seqIndex) reset() {
+	x.entries, x.dead = nil, 0
+}
@@ -0,0 +1,54 @@
+package cache
+
+import "testing"
+
+func TestKeysPage(t *testing.T) {
+	c := New(0)
+	for i := 0; i < 10; i++ {
+		c.Set(i, i)
+	}
+	var got []Key
+	keys, cursor := c.KeysPage(Cursor{}, 4)
+	got = append(got, keys...)
+	// Changes after the enumeration started don't disturb it.
+	c.Get(0)
+	c.Set(3, 3)
+	c.Remove(5)
+	c.Set(10, 10)
+	for cursor != (Cursor{}) {
+		keys, cursor = c.KeysPage(cursor, 4)
+		got = append(got, keys...)
+	}
+	want := []Key{0, 1, 2, 3, 4, 6, 7, 8, 9}
+	if len(got) != len(want) {
+		t.Fatalf("enumerated %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("enumerated %v, want %v", got, want)
+		}
+	}
+}
+
+func TestKeysPageRemovals(t *testing.T) {
+	c := New(0)
+	for i := 0; i < 100; i++ {
+		c.Set(i, i)
+	}
+	keys, cursor := c.KeysPage(Cursor{}, 10)
+	// Removing most keys compacts the index under the cursor.
+	for i := 0; i < 90; i++ {
+		c.Remove(i)
+	}
+	if len(c.bySeq.entries) >= 100 {
+		t.Fatalf("index holds %d entries after removing 90 of 100", len(c.bySeq.entries))
+	}
+	for cursor != (Cursor{}) {
+		var page []Key
+		page, cursor = c.KeysPage(cursor, 10)
+		keys = append(keys, page...)
+	}
+	if len(keys) != 20 || keys[10] != 90 || keys[19] != 99 {
+		t.Fatalf("enumerated %v, want 0-9 and 90-99", keys)
+	}
+}
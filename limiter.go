@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrWriteLimited is returned by TrySet when the write limiter
+// rejects the write.
+var ErrWriteLimited = errors.New("cache: write rate limited")
+
+// A Limiter admits or rejects writes. *rate.Limiter from
+// golang.org/x/time/rate implements it.
+type Limiter interface {
+	Allow() bool
+}
+
+// WithWriteLimiter makes Set, SetWithExpire and SetWithExpire2 drop
+// the writes l rejects, protecting the cache and its eviction callbacks
+// from pathological writers. Bulk inserts with SetAll aren't limited.
+// Rejected writes are counted in Stats.
+func WithWriteLimiter(l Limiter) Option {
+	return func(c *Cache) {
+		c.writeLimiter = l
+	}
+}
+
+// allowWrite reports whether the write limiter admits a write.
+func (c *Cache) allowWrite() bool {
+	if c.writeLimiter == nil || c.writeLimiter.Allow() {
+		return true
+	}
+	c.rejected.add(1)
+	return false
+}
+
+// TrySet is like Set, but reports whether the write limiter
+// rejected the write or the cache is draining.
+func (c *Cache) TrySet(key Key, value interface{}) error {
+	if c.isDraining() {
+		return ErrDraining
+	}
+	if !c.allowWrite() {
+		return ErrWriteLimited
+	}
+	expire := c.expireAt(c.defaultTTL(value))
+	c.mu.Lock()
+	c.add(key, value, expire)
+	c.mu.Unlock()
+	return nil
+}
+
+// waiter is implemented by limiters which can block until a write is
+// admitted, like *rate.Limiter.
+type waiter interface {
+	Wait(ctx context.Context) error
+}
+
+// SetWait is like Set, but instead of dropping a write the limiter
+// rejects, it blocks until the write is admitted or ctx is done,
+// returning the context's error in the latter case.
+// If the limiter has a Wait(context.Context) error method it's used,
+// otherwise the limiter is polled. Likewise, when adding key would
+// evict an entry held by Acquire, it waits for the entry to be
+// released or removed. It returns ErrDraining once Drain has been
+// called, like TrySet.
+func (c *Cache) SetWait(ctx context.Context, key Key, value interface{}) error {
+	if c.isDraining() {
+		return ErrDraining
+	}
+	if err := c.waitWrite(ctx); err != nil {
+		return err
+	}
+	expire := c.expireAt(c.defaultTTL(value))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.evictsHeld(key, value) {
+		if c.space == nil {
+			c.space = make(chan struct{})
+		}
+		space := c.space
+		c.mu.Unlock()
+		select {
+		case <-space:
+			c.mu.Lock()
+		case <-ctx.Done():
+			c.mu.Lock()
+			return ctx.Err()
+		}
+	}
+	if c.isDraining() {
+		return ErrDraining
+	}
+	c.actor = actorFrom(ctx)
+	defer func() { c.actor = "" }()
+	c.add(key, value, expire)
+	return nil
+}
+
+// evictsHeld reports whether adding key would evict the least recently
+// used entry while Acquire holds it. The caller must hold c.mu.
+func (c *Cache) evictsHeld(key Key, value interface{}) bool {
+	back := c.ll.Back()
+	if back == nil || back.Value.(*entry).refs == 0 {
+		return false
+	}
+	if _, ok := c.store.Get(key); ok {
+		return false
+	}
+	return c.MaxEntries > 0 && c.ll.Len() >= c.MaxEntries ||
+		c.MaxCost != 0 && atomic.LoadInt64(&c.cost)+c.entryCost(key, value) > c.MaxCost
+}
+
+// freed wakes the SetWait calls waiting for room. The caller must hold
+// c.mu.
+func (c *Cache) freed() {
+	if c.space != nil {
+		close(c.space)
+		c.space = nil
+	}
+}
+
+func (c *Cache) waitWrite(ctx context.Context) error {
+	if c.writeLimiter == nil {
+		return ctx.Err()
+	}
+	if w, ok := c.writeLimiter.(waiter); ok {
+		return w.Wait(ctx)
+	}
+	backoff := time.Millisecond
+	for !c.writeLimiter.Allow() {
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+		if backoff < 100*time.Millisecond {
+			backoff *= 2
+		}
+	}
+	return nil
+}
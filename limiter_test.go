@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// budget admits a fixed number of writes.
+type budget int
+
+func (b *budget) Allow() bool {
+	if *b == 0 {
+		return false
+	}
+	*b--
+	return true
+}
+
+func TestWriteLimiter(t *testing.T) {
+	b := budget(2)
+	ce := New(10, WithWriteLimiter(&b))
+	ce.Set("a", 1)
+	if err := ce.TrySet("b", 2); err != nil {
+		t.Fatalf("TrySet = %v within the budget", err)
+	}
+	if err := ce.TrySet("c", 3); err != ErrWriteLimited {
+		t.Fatalf("TrySet = %v over the budget, want ErrWriteLimited", err)
+	}
+	ce.Set("d", 4)
+	if ce.Has("c") || ce.Has("d") || ce.Len() != 2 {
+		t.Fatal("rejected writes were applied")
+	}
+	if s := ce.Stats(); s.Rejected != 2 {
+		t.Fatalf("Rejected = %d, want 2", s.Rejected)
+	}
+}
+
+func TestSetWait(t *testing.T) {
+	var b budget
+	ce := New(10, WithWriteLimiter(&b))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := ce.SetWait(ctx, "a", 1); err != context.DeadlineExceeded {
+		t.Fatalf("SetWait = %v without budget, want DeadlineExceeded", err)
+	}
+
+	w := &waitLimiter{ready: make(chan struct{})}
+	ce = New(10, WithWriteLimiter(w))
+	go close(w.ready)
+	if err := ce.SetWait(context.Background(), "a", 1); err != nil || !ce.Has("a") {
+		t.Fatalf("SetWait = %v", err)
+	}
+}
+
+// waitLimiter rejects writes until ready is closed.
+type waitLimiter struct {
+	ready chan struct{}
+}
+
+func (w *waitLimiter) Allow() bool {
+	return false
+}
+
+func (w *waitLimiter) Wait(ctx context.Context) error {
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestSetWaitHeld(t *testing.T) {
+	ce := New(1)
+	ce.Set("a", 1)
+	_, release, _ := ce.Acquire("a")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := ce.SetWait(ctx, "b", 2); err != context.DeadlineExceeded {
+		t.Fatalf("SetWait = %v while the only entry is held, want DeadlineExceeded", err)
+	}
+	if !ce.Has("a") || ce.Has("b") {
+		t.Fatal("SetWait evicted a held entry")
+	}
+	// Updating a key doesn't need room.
+	if err := ce.SetWait(context.Background(), "a", 3); err != nil {
+		t.Fatalf("SetWait = %v updating the held key", err)
+	}
+
+	time.AfterFunc(5*time.Millisecond, release)
+	if err := ce.SetWait(context.Background(), "b", 2); err != nil || !ce.Has("b") {
+		t.Fatalf("SetWait = %v after the release", err)
+	}
+}
+
+func TestSetWaitDraining(t *testing.T) {
+	ce := New(10)
+	if err := ce.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := ce.SetWait(context.Background(), "a", 1); err != ErrDraining {
+		t.Fatalf("SetWait = %v while draining, want ErrDraining", err)
+	}
+}
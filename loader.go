@@ -0,0 +1,360 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// A LoaderFunc loads the value of key on a cache miss.
+type LoaderFunc func(key Key) (interface{}, error)
+
+// call is a load in flight.
+type call struct {
+	wg  sync.WaitGroup
+	val interface{}
+	err error
+}
+
+// loads tracks the loads in flight, so concurrent misses on the
+// same key share a single loader execution.
+type loads struct {
+	mu    sync.Mutex
+	calls map[interface{}]*call
+	// attempts and backoff are set by WithLoaderRetry.
+	attempts int
+	backoff  BackoffFunc
+	// sem bounds the loader calls in flight, see WithMaxConcurrentLoads.
+	sem chan struct{}
+	// timeout is set by WithLoadTimeout.
+	timeout time.Duration
+}
+
+// ErrLoadTimeout is returned by GetOrLoad when the loader didn't
+// finish within the timeout set by WithLoadTimeout.
+var ErrLoadTimeout = errors.New("cache: load timed out")
+
+// LoaderPanicError is returned by GetOrLoad to every caller waiting
+// for a load whose loader panicked.
+type LoaderPanicError struct {
+	// Value is the value the loader panicked with.
+	Value interface{}
+	// Stack is the stack trace of the panic.
+	Stack []byte
+}
+
+func (e *LoaderPanicError) Error() string {
+	return fmt.Sprintf("cache: loader panicked: %v", e.Value)
+}
+
+// A BackoffFunc returns how long to wait before retrying a load
+// which failed attempt times.
+type BackoffFunc func(attempt int) time.Duration
+
+// ExponentialBackoff returns a BackoffFunc doubling the wait from base
+// up to max, with a random jitter of up to half the wait so that
+// loaders failing together don't retry in lockstep.
+func ExponentialBackoff(base, max time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		if d <= 0 {
+			return 0
+		}
+		return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+}
+
+// WithLoaderRetry makes GetOrLoad call a failing loader up to attempts
+// times, waiting backoff between the calls. The error of the last call
+// is returned. A nil backoff retries right away.
+func WithLoaderRetry(attempts int, backoff BackoffFunc) Option {
+	return func(c *Cache) {
+		c.loads.attempts = attempts
+		c.loads.backoff = backoff
+	}
+}
+
+// WithMaxConcurrentLoads bounds the loader calls in flight across all
+// keys to n, protecting slow backends from a cold cache stampede.
+// GetOrLoad waits for a slot when n loads are running already.
+// It panics if n isn't positive.
+func WithMaxConcurrentLoads(n int) Option {
+	if n <= 0 {
+		panic("cache: WithMaxConcurrentLoads n must be positive")
+	}
+	return func(c *Cache) {
+		c.loads.sem = make(chan struct{}, n)
+	}
+}
+
+// WithLoadTimeout bounds the time GetOrLoad waits for a load of a key,
+// retries included, to d. Past it the waiting callers get
+// ErrLoadTimeout and the next ones start a new load, so a hung backend
+// doesn't hold the key forever. The value of a load finishing late is
+// dropped. Every load then runs in a goroutine of its own, outside the
+// worker pool so hung loaders can't starve it, see WithWorkers, which
+// keeps running until the loader returns; WithMaxConcurrentLoads bounds the loaders
+// running at once.
+func WithLoadTimeout(d time.Duration) Option {
+	return func(c *Cache) {
+		c.loads.timeout = d
+	}
+}
+
+// run calls loader within the timeout set by WithLoadTimeout.
+func (l *loads) run(key Key, loader LoaderFunc) (interface{}, error) {
+	if l.timeout <= 0 {
+		return l.call(key, loader)
+	}
+	type result struct {
+		val interface{}
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := l.call(key, loader)
+		done <- result{v, err}
+	}()
+	t := time.NewTimer(l.timeout)
+	defer t.Stop()
+	select {
+	case r := <-done:
+		return r.val, r.err
+	case <-t.C:
+		return nil, ErrLoadTimeout
+	}
+}
+
+// call calls loader, retrying it as set by WithLoaderRetry.
+// A panic isn't retried, it's returned as a *LoaderPanicError.
+func (l *loads) call(key Key, loader LoaderFunc) (v interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			v, err = nil, &LoaderPanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	for attempt := 1; ; attempt++ {
+		v, err = l.attempt(key, loader)
+		if err == nil || attempt >= l.attempts {
+			return v, err
+		}
+		if l.backoff != nil {
+			time.Sleep(l.backoff(attempt))
+		}
+	}
+}
+
+// attempt calls loader once, holding a slot of WithMaxConcurrentLoads.
+func (l *loads) attempt(key Key, loader LoaderFunc) (interface{}, error) {
+	if l.sem != nil {
+		l.sem <- struct{}{}
+		// Even a panicking loader gives its slot back.
+		defer func() { <-l.sem }()
+	}
+	return loader(key)
+}
+
+// GetOrLoad looks up a key's value from the cache, loading and adding
+// it with loader on a miss. Concurrent misses on the same key wait for
+// a single loader call and share its result. Errors are returned to
+// every waiter and not cached, see WithLoaderRetry for retrying them
+// and WithServeStaleOnError for serving the expired value instead.
+// A panic of loader is recovered and returned as a *LoaderPanicError.
+//
+// An entry past its soft TTL, see SetWithExpire2, is returned as is
+// while loader refreshes it in the background.
+func (c *Cache) GetOrLoad(key Key, loader LoaderFunc) (interface{}, error) {
+	r, err := c.GetOrLoadResult(key, loader)
+	return r.Value, err
+}
+
+// GetOrLoadResult is like GetOrLoad, also reporting whether the value
+// is stale like GetResult. Values served because loading failed, see
+// WithServeStaleOnError, are flagged as stale.
+func (c *Cache) GetOrLoadResult(key Key, loader LoaderFunc) (Result, error) {
+	r := c.GetResult(key)
+	if r.Ok {
+		if r.Stale {
+			// Serve the stale value while refreshing it in the background,
+			// unless a load of key is in flight already.
+			soft, hard := r.SoftTTL, r.TTL
+			if cl, first := c.startLoad(key); first {
+				refresh := func() {
+					c.runLoad(key, cl, loader, func(v interface{}) {
+						c.SetWithExpire2(key, v, soft, hard)
+					})
+				}
+				if !c.spawn(refresh) {
+					cl.err = ErrQueueFull
+					c.endLoad(key, cl)
+				}
+			}
+		}
+		return r, nil
+	}
+	v, err := c.load(key, loader, func(v interface{}) {
+		c.Set(key, v)
+	})
+	if err != nil {
+		if v, ok := c.staleValue(key); ok {
+			return Result{Value: v, Ok: true, Stale: true}, nil
+		}
+		return Result{Value: v}, err
+	}
+	return Result{Value: v, Ok: true}, nil
+}
+
+// InFlight returns the keys being loaded by GetOrLoad, showing whether
+// slowness comes from the cache or the loaders.
+func (c *Cache) InFlight() []Key {
+	c.loads.mu.Lock()
+	defer c.loads.mu.Unlock()
+	keys := make([]Key, 0, len(c.loads.calls))
+	for key := range c.loads.calls {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// load calls loader unless a load of key is in flight already,
+// and adds the value with set.
+func (c *Cache) load(key Key, loader LoaderFunc, set func(v interface{})) (interface{}, error) {
+	cl, first := c.startLoad(key)
+	if first {
+		c.runLoad(key, cl, loader, set)
+	} else {
+		cl.wg.Wait()
+	}
+	return cl.val, cl.err
+}
+
+// startLoad returns the load of key in flight, and whether it's a new
+// one the caller must run with runLoad.
+func (c *Cache) startLoad(key Key) (*call, bool) {
+	c.loads.mu.Lock()
+	defer c.loads.mu.Unlock()
+	if c.loads.calls == nil {
+		c.loads.calls = make(map[interface{}]*call)
+	}
+	if cl, ok := c.loads.calls[key]; ok {
+		return cl, false
+	}
+	cl := new(call)
+	cl.wg.Add(1)
+	c.loads.calls[key] = cl
+	return cl, true
+}
+
+// runLoad runs the load cl started by startLoad.
+func (c *Cache) runLoad(key Key, cl *call, loader LoaderFunc, set func(v interface{})) {
+	// The waiters are released even if set panics.
+	defer c.endLoad(key, cl)
+
+	cl.val, cl.err = c.loads.run(key, loader)
+	if cl.err == nil {
+		set(cl.val)
+	}
+}
+
+// endLoad releases the waiters of the load cl of key.
+func (c *Cache) endLoad(key Key, cl *call) {
+	c.loads.mu.Lock()
+	delete(c.loads.calls, key)
+	c.loads.mu.Unlock()
+	cl.wg.Done()
+}
+
+// PartialError is returned by GetMultiOrLoad when some of the keys
+// couldn't be loaded, alongside the values of the others.
+type PartialError struct {
+	// TimedOut lists the keys still loading when the context was done.
+	TimedOut []Key
+	// Failed holds the errors of the keys whose loader failed.
+	Failed map[Key]error
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("cache: %d keys timed out, %d failed to load", len(e.TimedOut), len(e.Failed))
+}
+
+// multiLoaders bounds the concurrent loads of a GetMultiOrLoad call.
+const multiLoaders = 16
+
+// GetMultiOrLoad looks up the values of keys like GetOrLoad, loading
+// the missing ones concurrently, up to 16 at a time. The loads run in
+// goroutines of the call rather than on the worker pool, which may be
+// the caller's. If ctx is done before every load
+// finished, or some loads failed, it returns the values it has along
+// with a *PartialError, so callers can degrade gracefully rather than
+// fail the whole batch. The loads which timed out keep running and
+// add their values when they finish; the ones which didn't start yet
+// are abandoned.
+func (c *Cache) GetMultiOrLoad(ctx context.Context, keys []Key, loader LoaderFunc) (map[Key]interface{}, error) {
+	type result struct {
+		key Key
+		val interface{}
+		err error
+	}
+	pending := make(map[Key]bool, len(keys))
+	queue := make(chan Key, len(keys))
+	for _, key := range keys {
+		if pending[key] {
+			continue
+		}
+		pending[key] = true
+		queue <- key
+	}
+	close(queue)
+	results := make(chan result, len(pending))
+	n := len(pending)
+	if n > multiLoaders {
+		n = multiLoaders
+	}
+	for i := 0; i < n; i++ {
+		go func() {
+			for key := range queue {
+				if ctx.Err() != nil {
+					return
+				}
+				v, err := c.GetOrLoad(key, loader)
+				results <- result{key, v, err}
+			}
+		}()
+	}
+
+	values := make(map[Key]interface{}, len(pending))
+	var perr PartialError
+	for len(pending) > 0 {
+		select {
+		case r := <-results:
+			delete(pending, r.key)
+			if r.err != nil {
+				if perr.Failed == nil {
+					perr.Failed = make(map[Key]error)
+				}
+				perr.Failed[r.key] = r.err
+				continue
+			}
+			values[r.key] = r.val
+		case <-ctx.Done():
+			for key := range pending {
+				perr.TimedOut = append(perr.TimedOut, key)
+			}
+			return values, &perr
+		}
+	}
+	if perr.Failed != nil {
+		return values, &perr
+	}
+	return values, nil
+}
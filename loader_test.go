@@ -0,0 +1,259 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrLoad(t *testing.T) {
+	ce := New(10)
+	var calls int32
+	loader := func(key Key) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		return key.(string) + "!", nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := ce.GetOrLoad("a", loader); err != nil || v != "a!" {
+				t.Errorf("GetOrLoad = %v, %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Fatalf("loader called %d times, want 1", calls)
+	}
+
+	errFail := errors.New("fail")
+	if _, err := ce.GetOrLoad("b", func(Key) (interface{}, error) { return nil, errFail }); err != errFail {
+		t.Fatalf("GetOrLoad error = %v, want %v", err, errFail)
+	}
+	if ce.Has("b") {
+		t.Fatal("a failed load was cached")
+	}
+}
+
+func TestGetMultiOrLoad(t *testing.T) {
+	ce := New(10)
+	ce.Set("cached", 0)
+	errFail := errors.New("fail")
+	loader := func(key Key) (interface{}, error) {
+		switch key {
+		case "slow":
+			time.Sleep(time.Second)
+		case "bad":
+			return nil, errFail
+		}
+		return key.(string) + "!", nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	values, err := ce.GetMultiOrLoad(ctx, []Key{"cached", "a", "slow", "bad", "a"}, loader)
+	perr, ok := err.(*PartialError)
+	if !ok {
+		t.Fatalf("GetMultiOrLoad error = %v, want a *PartialError", err)
+	}
+	if len(perr.TimedOut) != 1 || perr.TimedOut[0] != "slow" || perr.Failed["bad"] != errFail {
+		t.Fatalf("PartialError = %+v", perr)
+	}
+	if len(values) != 2 || values["cached"] != 0 || values["a"] != "a!" {
+		t.Fatalf("values = %v", values)
+	}
+
+	values, err = ce.GetMultiOrLoad(context.Background(), []Key{"a", "b"}, loader)
+	if err != nil || len(values) != 2 {
+		t.Fatalf("GetMultiOrLoad = %v, %v", values, err)
+	}
+}
+
+func TestLoaderRetry(t *testing.T) {
+	ce := New(10, WithLoaderRetry(3, ExponentialBackoff(time.Millisecond, 4*time.Millisecond)))
+	calls := 0
+	flaky := func(key Key) (interface{}, error) {
+		calls++
+		if calls < 3 {
+			return nil, errors.New("transient")
+		}
+		return "ok", nil
+	}
+	if v, err := ce.GetOrLoad("a", flaky); err != nil || v != "ok" || calls != 3 {
+		t.Fatalf("GetOrLoad = %v, %v after %d calls", v, err, calls)
+	}
+
+	calls = 0
+	errFail := errors.New("fail")
+	if _, err := ce.GetOrLoad("b", func(Key) (interface{}, error) {
+		calls++
+		return nil, errFail
+	}); err != errFail || calls != 3 {
+		t.Fatalf("GetOrLoad error = %v after %d calls", err, calls)
+	}
+
+	backoff := ExponentialBackoff(10*time.Millisecond, 30*time.Millisecond)
+	for attempt, max := range []time.Duration{10, 20, 30, 30} {
+		if d := backoff(attempt + 1); d < max*time.Millisecond/2 || d > max*time.Millisecond {
+			t.Errorf("backoff(%d) = %v, want up to %v", attempt+1, d, max*time.Millisecond)
+		}
+	}
+}
+
+func TestGetMultiOrLoadBounded(t *testing.T) {
+	ce := New(0)
+	var running, peak int32
+	keys := make([]Key, 100)
+	for i := range keys {
+		keys[i] = i
+	}
+	values, err := ce.GetMultiOrLoad(context.Background(), keys, func(key Key) (interface{}, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return key, nil
+	})
+	if err != nil || len(values) != 100 {
+		t.Fatalf("GetMultiOrLoad = %d values, %v", len(values), err)
+	}
+	if peak > multiLoaders {
+		t.Fatalf("%d loads ran concurrently, want at most %d", peak, multiLoaders)
+	}
+}
+
+func TestMaxConcurrentLoads(t *testing.T) {
+	ce := New(100, WithMaxConcurrentLoads(2))
+	var running, peak int32
+	loader := func(key Key) (interface{}, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return key, nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ce.GetOrLoad(i, loader)
+		}(i)
+	}
+	wg.Wait()
+	if peak != 2 {
+		t.Fatalf("%d loads ran concurrently, want 2", peak)
+	}
+}
+
+func TestMaxConcurrentLoadsZero(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("WithMaxConcurrentLoads(0) didn't panic")
+		}
+	}()
+	New(10, WithMaxConcurrentLoads(0))
+}
+
+func TestMaxConcurrentLoadsPanic(t *testing.T) {
+	ce := New(10, WithMaxConcurrentLoads(1))
+	ce.GetOrLoad("a", func(Key) (interface{}, error) { panic("boom") })
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ce.GetOrLoad("b", func(Key) (interface{}, error) { return 1, nil })
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the panicking loader kept its slot")
+	}
+}
+
+func TestLoaderPanic(t *testing.T) {
+	ce := New(10)
+	started, release := make(chan struct{}), make(chan struct{})
+	errs := make(chan error, 2)
+	go func() {
+		_, err := ce.GetOrLoad("a", func(Key) (interface{}, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+		errs <- err
+	}()
+	<-started
+	go func() {
+		_, err := ce.GetOrLoad("a", func(Key) (interface{}, error) { return 1, nil })
+		errs <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+	close(release)
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if perr, ok := err.(*LoaderPanicError); !ok || perr.Value != "boom" {
+				t.Fatalf("GetOrLoad error = %v, want the loader panic", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("a waiter of the panicking load is blocked")
+		}
+	}
+	if v, err := ce.GetOrLoad("a", func(Key) (interface{}, error) { return 2, nil }); err != nil || v != 2 {
+		t.Fatalf("GetOrLoad after the panic = %v, %v", v, err)
+	}
+}
+
+func TestLoadTimeout(t *testing.T) {
+	ce := New(10, WithLoadTimeout(20*time.Millisecond))
+	hang := make(chan struct{})
+	defer close(hang)
+	if _, err := ce.GetOrLoad("a", func(Key) (interface{}, error) {
+		<-hang
+		return "late", nil
+	}); err != ErrLoadTimeout {
+		t.Fatalf("GetOrLoad error = %v, want ErrLoadTimeout", err)
+	}
+	// The key isn't held by the hung load.
+	if v, err := ce.GetOrLoad("a", func(Key) (interface{}, error) { return "ok", nil }); err != nil || v != "ok" {
+		t.Fatalf("GetOrLoad = %v, %v", v, err)
+	}
+}
+
+func TestInFlight(t *testing.T) {
+	ce := New(10)
+	started, release := make(chan struct{}), make(chan struct{})
+	go ce.GetOrLoad("a", func(Key) (interface{}, error) {
+		close(started)
+		<-release
+		return 1, nil
+	})
+	<-started
+	if keys := ce.InFlight(); len(keys) != 1 || keys[0] != "a" {
+		t.Fatalf("InFlight() = %v", keys)
+	}
+	if n := ce.Stats().InFlight; n != 1 {
+		t.Fatalf("Stats().InFlight = %d", n)
+	}
+	close(release)
+	ce.GetOrLoad("a", nil)
+	if keys := ce.InFlight(); len(keys) != 0 {
+		t.Fatalf("InFlight() = %v after the load finished", keys)
+	}
+}
@@ -0,0 +1,113 @@
+package cache
+
+import "sync"
+
+// Map is a bounded replacement for sync.Map: it has the methods of
+// sync.Map with the same semantics, backed by a Cache, so code written
+// against sync.Map gains eviction and TTLs by swapping the type.
+// Unlike with sync.Map, stored keys may be evicted or expire at any
+// time. The zero Map is empty, unbounded and ready for use.
+type Map struct {
+	once sync.Once
+	c    *Cache
+}
+
+// NewMap returns a Map backed by New(maxEntries, opts...).
+func NewMap(maxEntries int, opts ...Option) *Map {
+	return &Map{c: New(maxEntries, opts...)}
+}
+
+// Cache returns the cache backing m, e.g. for its Stats.
+func (m *Map) Cache() *Cache {
+	m.once.Do(func() {
+		if m.c == nil {
+			m.c = Unbounded()
+		}
+	})
+	return m.c
+}
+
+// Load returns the value stored for key, if any.
+func (m *Map) Load(key interface{}) (value interface{}, ok bool) {
+	return m.Cache().Get(key)
+}
+
+// Store sets the value for key.
+func (m *Map) Store(key, value interface{}) {
+	m.Cache().Set(key, value)
+}
+
+// LoadOrStore returns the existing value for key if present.
+// Otherwise, it stores and returns value. loaded reports whether
+// the value was loaded.
+func (m *Map) LoadOrStore(key, value interface{}) (actual interface{}, loaded bool) {
+	c := m.Cache()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store != nil {
+		if ele, hit := c.store.Get(key); hit {
+			e := ele.Value.(*entry)
+			now := c.now()
+			if !e.expired(now) {
+				c.touch(ele)
+				c.hit(e, now)
+				return e.value, true
+			}
+			c.expire(ele)
+		}
+	}
+	c.miss(key)
+	if c.allowWrite() {
+		c.add(key, value, c.expireAt(c.defaultTTL(value)))
+	}
+	return value, false
+}
+
+// LoadAndDelete deletes the value for key, returning the previous
+// value if any. loaded reports whether key was present.
+func (m *Map) LoadAndDelete(key interface{}) (value interface{}, loaded bool) {
+	c := m.Cache()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil {
+		return nil, false
+	}
+	if ele, hit := c.store.Get(key); hit {
+		if e := ele.Value.(*entry); !e.expired(c.now()) {
+			value, loaded = e.value, true
+		}
+	}
+	c.remove(key)
+	return value, loaded
+}
+
+// Delete deletes the value for key.
+func (m *Map) Delete(key interface{}) {
+	m.Cache().Remove(key)
+}
+
+// Range calls f for each key and value present in m, most recently
+// used first. If f returns false, Range stops the iteration. Like for
+// sync.Map, f may modify m: Range copies the entries before calling f
+// and doesn't correspond to a consistent snapshot of m when it does.
+func (m *Map) Range(f func(key, value interface{}) bool) {
+	c := m.Cache()
+	c.mu.Lock()
+	if c.store == nil {
+		c.mu.Unlock()
+		return
+	}
+	now := c.now()
+	entries := make([]Entry, 0, c.ll.Len())
+	for ele := c.ll.Front(); ele != nil; ele = ele.Next() {
+		if e := ele.Value.(*entry); !e.expired(now) {
+			entries = append(entries, Entry{Key: e.key, Value: e.value})
+		}
+	}
+	c.mu.Unlock()
+	for _, e := range entries {
+		if !f(e.Key, e.Value) {
+			return
+		}
+	}
+}
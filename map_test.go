@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	var m Map
+	if _, ok := m.Load("a"); ok {
+		t.Fatal("Load found a key in an empty Map")
+	}
+	m.Store("a", 1)
+	if v, ok := m.Load("a"); !ok || v != 1 {
+		t.Fatalf("Load = %v, %v", v, ok)
+	}
+	if v, loaded := m.LoadOrStore("a", 2); !loaded || v != 1 {
+		t.Fatalf("LoadOrStore of a present key = %v, %v", v, loaded)
+	}
+	if v, loaded := m.LoadOrStore("b", 2); loaded || v != 2 {
+		t.Fatalf("LoadOrStore of a missing key = %v, %v", v, loaded)
+	}
+	if v, loaded := m.LoadAndDelete("b"); !loaded || v != 2 {
+		t.Fatalf("LoadAndDelete = %v, %v", v, loaded)
+	}
+	if _, loaded := m.LoadAndDelete("b"); loaded {
+		t.Fatal("LoadAndDelete loaded a deleted key")
+	}
+	m.Store("c", 3)
+	m.Delete("a")
+
+	seen := map[interface{}]interface{}{}
+	m.Range(func(k, v interface{}) bool {
+		// f may modify the map.
+		m.Store("d", 4)
+		seen[k] = v
+		return true
+	})
+	if len(seen) != 1 || seen["c"] != 3 {
+		t.Fatalf("Range saw %v, want only c", seen)
+	}
+}
+
+func TestMapBounded(t *testing.T) {
+	m := NewMap(0, WithMaxCost(2))
+	m.Store("a", 1)
+	m.Store("b", 2)
+	m.Store("c", 3)
+	if _, ok := m.Load("a"); ok {
+		t.Fatal("the Map wasn't bounded")
+	}
+	if n := m.Cache().Len(); n != 2 {
+		t.Fatalf("Len() = %d, want 2", n)
+	}
+}
+
+func TestMapLoadOrStoreConcurrent(t *testing.T) {
+	var m Map
+	var wg sync.WaitGroup
+	stored := make(chan int, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, loaded := m.LoadOrStore("k", i); !loaded {
+				stored <- i
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(stored)
+	if len(stored) != 1 {
+		t.Fatalf("%d LoadOrStore calls stored their value, want 1", len(stored))
+	}
+}
@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+)
+
+// memWatcher samples the heap usage of the process.
+type memWatcher struct {
+	target   float64
+	interval time.Duration
+	// read returns the heap usage and the memory limit in bytes,
+	// a zero limit means there is none.
+	read func() (heap, limit uint64)
+}
+
+// WithMemoryWatcher samples the process heap every
+// second and evicting the oldest entries while the heap exceeds target
+// (e.g. 0.8) of the memory limit set by GOMEMLIMIT, so the cache's
+// growth doesn't get the process killed. Entries are evicted in
+// batches of 1% so the cache shrinks gradually as the GC catches up.
+// Without a memory limit the watcher does nothing.
+// The watcher runs until Close is called.
+func WithMemoryWatcher(target float64) Option {
+	return func(c *Cache) {
+		c.memWatcher = &memWatcher{
+			target:   target,
+			interval: time.Second,
+			read:     readHeapMetrics,
+		}
+	}
+}
+
+func readHeapMetrics() (heap, limit uint64) {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/heap/objects:bytes"},
+		{Name: "/gc/gomemlimit:bytes"},
+	}
+	metrics.Read(samples)
+	// Older runtimes don't report a memory limit.
+	if samples[1].Value.Kind() != metrics.KindUint64 {
+		return samples[0].Value.Uint64(), 0
+	}
+	return samples[0].Value.Uint64(), samples[1].Value.Uint64()
+}
+
+// checkMemory evicts a batch of entries if the heap is over the target.
+func (c *Cache) checkMemory() {
+	heap, limit := c.memWatcher.read()
+	// math.MaxInt64 is the runtime's way to say there is no limit.
+	if limit == 0 || limit >= 1<<63-1 || float64(heap) <= c.memWatcher.target*float64(limit) {
+		return
+	}
+	defer c.afterPurge()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil {
+		return
+	}
+	n := c.ll.Len() / 100
+	if n == 0 {
+		n = 1
+	}
+	for ; n > 0 && c.ll.Len() > 0; n-- {
+		c.stats.evictions++
+		c.RemoveOldest()
+	}
+}
+
+// WithCompactOnPurge makes the cache Compact itself after Clear, Reset
+// and evictions by the memory watcher, so long-lived processes return
+// the memory to the OS after traffic spikes.
+func WithCompactOnPurge() Option {
+	return func(c *Cache) {
+		c.compactOnPurge = true
+	}
+}
+
+func (c *Cache) afterPurge() {
+	if c.compactOnPurge {
+		c.Compact()
+	}
+}
+
+// Compact rebuilds the internal structures of the cache at the size of
+// its current contents, e.g. Go maps never shrink on their own, and
+// then returns as much memory as possible to the OS.
+// It forces a garbage collection, so it's costly.
+func (c *Cache) Compact() {
+	c.mu.Lock()
+	c.rebuildStore()
+	c.mu.Unlock()
+	debug.FreeOSMemory()
+}
+
+// rebuildStore moves the entries into a new store.
+// The caller must hold c.mu.
+func (c *Cache) rebuildStore() {
+	if c.store == nil {
+		return
+	}
+	store := c.newStore()
+	for e := c.ll.Back(); e != nil; e = e.Prev() {
+		store.Set(e.Value.(*entry).key, e)
+	}
+	c.store = store
+	c.shrink.peak = c.ll.Len()
+}
+
+const (
+	defaultShrinkRatio = 0.25
+	// shrinkMinPeak is the smallest peak worth shrinking from.
+	shrinkMinPeak = 1024
+)
+
+// shrinker decides when the store should be rebuilt because it held
+// far more entries at its peak than it does now. Go maps never shrink,
+// so without it the buckets of a map which held millions of entries
+// stay allocated forever.
+type shrinker struct {
+	ratio float64
+	// peak is the most entries held since the store was built.
+	peak int
+}
+
+// WithMapShrink sets the fraction of its peak size below which the
+// store is rebuilt, 0.25 by default. As the peak is reset to the
+// size after the rebuild, the cache has to lose the same fraction
+// again before the next one, which keeps it from thrashing.
+// A ratio of zero disables shrinking.
+func WithMapShrink(ratio float64) Option {
+	return func(c *Cache) {
+		c.shrink.ratio = ratio
+	}
+}
+
+func (s *shrinker) grew(n int) {
+	if n > s.peak {
+		s.peak = n
+	}
+}
+
+// shrunk reports whether the store holding n entries should be rebuilt,
+// and resets the peak if so.
+func (s *shrinker) shrunk(n int) bool {
+	if s.peak < shrinkMinPeak || float64(n) >= s.ratio*float64(s.peak) {
+		return false
+	}
+	s.peak = n
+	return true
+}
@@ -0,0 +1,94 @@
+package cache
+
+import "testing"
+
+func TestMemoryWatcher(t *testing.T) {
+	heap := uint64(100)
+	ce := New(0)
+	ce.memWatcher = &memWatcher{
+		target: 0.5,
+		read:   func() (uint64, uint64) { return heap, 100 },
+	}
+	for i := 0; i < 300; i++ {
+		ce.Set(i, i)
+	}
+
+	ce.checkMemory()
+	if n := ce.Len(); n != 297 {
+		t.Fatalf("Len = %d after a pressured check, want 297", n)
+	}
+	if ce.Has(0) || !ce.Has(3) {
+		t.Fatal("the watcher should evict the oldest entries")
+	}
+	heap = 40
+	ce.checkMemory()
+	if n := ce.Len(); n != 297 {
+		t.Fatalf("Len = %d after a relaxed check, want 297", n)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	stores := 0
+	ce := New(0, WithCompactOnPurge(), WithStore(func() Store {
+		stores++
+		return NewMapStore()
+	}))
+	for i := 0; i < 1000; i++ {
+		ce.Set(i, i)
+	}
+	for i := 0; i < 990; i++ {
+		ce.Remove(i)
+	}
+	ce.Compact()
+	if stores != 2 {
+		t.Fatalf("Compact created %d stores, want 2", stores)
+	}
+	for i := 990; i < 1000; i++ {
+		if v, ok := ce.Get(i); !ok || v != i {
+			t.Fatalf("Get(%d) = %v, %v after Compact", i, v, ok)
+		}
+	}
+	ce.Reset()
+	if stores != 3 {
+		t.Fatalf("Reset didn't compact, %d stores", stores)
+	}
+}
+
+func TestMapShrink(t *testing.T) {
+	stores := 0
+	ce := New(0, WithStore(func() Store {
+		stores++
+		return NewMapStore()
+	}))
+	for i := 0; i < 4000; i++ {
+		ce.Set(i, i)
+	}
+	for i := 0; i < 3000; i++ {
+		ce.Remove(i)
+	}
+	if stores != 1 {
+		t.Fatalf("store rebuilt at %d/4000 entries", ce.Len())
+	}
+	ce.Remove(3000)
+	if stores != 2 {
+		t.Fatalf("store not rebuilt below a quarter of its peak")
+	}
+	// The new peak is 999, too small to shrink again.
+	for i := 3001; i < 4000; i++ {
+		ce.Remove(i)
+	}
+	if stores != 2 || ce.Len() != 0 {
+		t.Fatalf("%d stores, %d entries", stores, ce.Len())
+	}
+
+	ce = New(0, WithMapShrink(0))
+	for i := 0; i < 4000; i++ {
+		ce.Set(i, i)
+	}
+	for i := 0; i < 4000; i++ {
+		ce.Remove(i)
+	}
+	if ce.shrink.peak != 4000 {
+		t.Fatal("disabled shrinking rebuilt the store")
+	}
+}
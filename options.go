@@ -0,0 +1,132 @@
+package cache
+
+import "time"
+
+// An Option configures a Cache created by New.
+type Option func(*Cache)
+
+// WithMaxTTL bounds the TTL of every entry to at most d.
+// Entries added without an expiration expire after d as well,
+// so a misconfigured caller can't cache data forever.
+func WithMaxTTL(d time.Duration) Option {
+	return func(c *Cache) {
+		c.updateTTLs(func(b *ttlBounds) { b.max = d })
+	}
+}
+
+// WithMinTTL bounds the TTL of entries added with an expiration
+// to at least d.
+func WithMinTTL(d time.Duration) Option {
+	return func(c *Cache) {
+		c.updateTTLs(func(b *ttlBounds) { b.min = d })
+	}
+}
+
+// WithDefaultTTL sets the TTL of entries added without one.
+// It's clamped like the TTLs passed to SetWithExpire.
+func WithDefaultTTL(d time.Duration) Option {
+	return func(c *Cache) {
+		c.updateTTLs(func(b *ttlBounds) { b.def = d })
+	}
+}
+
+// ttlBounds are the TTL settings. ApplyConfig may change them while
+// the cache is in use, so they're read without holding c.mu.
+type ttlBounds struct {
+	// min and max bound the TTL of every entry, zero means no bound.
+	min, max time.Duration
+	// def is the TTL of entries added without one.
+	def time.Duration
+}
+
+func (c *Cache) ttlBounds() ttlBounds {
+	b, _ := c.ttls.Load().(ttlBounds)
+	return b
+}
+
+func (c *Cache) updateTTLs(fn func(b *ttlBounds)) {
+	b := c.ttlBounds()
+	fn(&b)
+	c.ttls.Store(b)
+}
+
+// WithTTLFromValue derives the TTL of entries added without one from
+// their value, for values carrying their own freshness like DNS records
+// or signed tokens. A TTL of zero means the entry doesn't expire.
+// The derived TTL is clamped like the ones passed to SetWithExpire.
+func WithTTLFromValue(ttl func(value interface{}) time.Duration) Option {
+	return func(c *Cache) {
+		c.ttlFromValue = ttl
+	}
+}
+
+// defaultTTL returns the TTL of value added without one.
+func (c *Cache) defaultTTL(value interface{}) time.Duration {
+	if c.ttlFromValue == nil {
+		return c.ttlBounds().def
+	}
+	return c.ttlFromValue(value)
+}
+
+// clampTTL applies the TTL bounds to ttl. A zero ttl means the
+// entry doesn't expire and is only bounded by the maximum TTL.
+func (c *Cache) clampTTL(ttl time.Duration) time.Duration {
+	b := c.ttlBounds()
+	if ttl > 0 && b.min > 0 && ttl < b.min {
+		ttl = b.min
+	}
+	if b.max > 0 && (ttl <= 0 || ttl > b.max) {
+		ttl = b.max
+	}
+	return ttl
+}
+
+// expireAt returns the expire time of an entry with the given TTL,
+// or zero if it never expires.
+func (c *Cache) expireAt(ttl time.Duration) int64 {
+	ttl = c.clampTTL(ttl)
+	if ttl <= 0 {
+		return 0
+	}
+	return c.now() + int64(ttl)
+}
+
+// WithValueEqual makes Set skip replacing the value of an entry when
+// equal reports the new one is the same, only renewing its expiration.
+// This cuts the churn from periodic refreshers which usually produce
+// unchanged data: the entry keeps its age and isn't reweighed.
+func WithValueEqual(equal func(a, b interface{}) bool) Option {
+	return func(c *Cache) {
+		c.valueEqual = equal
+	}
+}
+
+// WithMaxEntryAge bounds the time an entry is served after its value
+// was set to d, however often it's used or its expiration renewed,
+// for policies forbidding to serve data older than a bound. Entries
+// past the age expire like entries past their TTL.
+func WithMaxEntryAge(d time.Duration) Option {
+	return func(c *Cache) {
+		c.maxAge = int64(d)
+	}
+}
+
+// capAge returns expire bounded by the maximum age of e.
+func (c *Cache) capAge(e *entry, expire int64) int64 {
+	if c.maxAge <= 0 {
+		return expire
+	}
+	if limit := e.created + c.maxAge; expire == 0 || expire > limit {
+		return limit
+	}
+	return expire
+}
+
+// WithMaxHits makes entries expire once their value has been served
+// n times, e.g. for one-time credentials or to force hot keys to be
+// revalidated periodically through GetOrLoad. See Info for the count.
+func WithMaxHits(n int) Option {
+	return func(c *Cache) {
+		c.maxHits = uint64(n)
+	}
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTTLBounds(t *testing.T) {
+	ce := New(10, WithMinTTL(time.Minute), WithMaxTTL(time.Hour))
+	cases := []struct {
+		ttl, want time.Duration
+	}{
+		{0, time.Hour},
+		{time.Second, time.Minute},
+		{10 * time.Minute, 10 * time.Minute},
+		{48 * time.Hour, time.Hour},
+	}
+	for _, tc := range cases {
+		if got := ce.clampTTL(tc.ttl); got != tc.want {
+			t.Errorf("clampTTL(%v) = %v, want %v", tc.ttl, got, tc.want)
+		}
+	}
+
+	ce.Set("forever", 1)
+	ele, _ := ce.store.Get("forever")
+	if ele.Value.(*entry).expire == 0 {
+		t.Error("Set with a max TTL should expire")
+	}
+}
+
+type record struct {
+	ttl time.Duration
+}
+
+func TestTTLFromValue(t *testing.T) {
+	ce := New(10, WithTTLFromValue(func(v interface{}) time.Duration {
+		if r, ok := v.(record); ok {
+			return r.ttl
+		}
+		return 0
+	}))
+	ce.Set("rec", record{ttl: time.Hour})
+	ce.Set("plain", 1)
+	ce.SetWithExpire("explicit", record{ttl: time.Hour}, time.Minute)
+
+	expire := func(key Key) int64 {
+		ele, _ := ce.store.Get(key)
+		return ele.Value.(*entry).expire
+	}
+	now := nanotime()
+	if got := expire("rec"); got < now+int64(59*time.Minute) {
+		t.Errorf("rec expires at %d, want in an hour", got)
+	}
+	if got := expire("plain"); got != 0 {
+		t.Errorf("plain expires at %d, want never", got)
+	}
+	if got := expire("explicit"); got > now+int64(2*time.Minute) {
+		t.Errorf("explicit expires at %d, want in a minute", got)
+	}
+}
+
+func TestValueEqual(t *testing.T) {
+	costs := 0
+	ce := New(10,
+		WithValueEqual(func(a, b interface{}) bool { return a == b }),
+		WithCost(func(Key, interface{}) int64 {
+			costs++
+			return 1
+		}))
+	ce.Set("a", 1)
+	ele, _ := ce.store.Get("a")
+	created := ele.Value.(*entry).created
+	ce.SetWithExpire("a", 1, time.Hour)
+	e := ele.Value.(*entry)
+	if e.created != created || costs != 1 {
+		t.Fatal("an equal value was rewritten")
+	}
+	if e.expire == 0 {
+		t.Fatal("an equal value didn't renew its expiration")
+	}
+	ce.Set("a", 2)
+	if v, _ := ce.Get("a"); v != 2 || costs != 2 {
+		t.Fatalf("Get = %v after setting a different value", v)
+	}
+}
+
+func TestMaxEntryAge(t *testing.T) {
+	ce := New(10, WithMaxEntryAge(20*time.Millisecond), WithValueEqual(func(a, b interface{}) bool { return a == b }))
+	ce.Set("a", 1)
+	ce.SetWithExpire("b", 1, time.Hour)
+	for i := 0; i < 5; i++ {
+		time.Sleep(5 * time.Millisecond)
+		ce.Get("a")
+		// Renewing an unchanged value doesn't make it younger.
+		ce.SetWithExpire("b", 1, time.Hour)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if _, ok := ce.Get("a"); ok {
+		t.Fatal("a constantly used entry outlived the max age")
+	}
+	if _, ok := ce.Get("b"); ok {
+		t.Fatal("a renewed entry outlived the max age")
+	}
+	ce.SetWithExpire("b", 2, time.Hour)
+	if _, ok := ce.Get("b"); !ok {
+		t.Fatal("a new value was expired")
+	}
+}
@@ -0,0 +1,183 @@
+// Package policytest checks that implementations of cache.Store behave
+// as the cache expects, so third-party stores can validate themselves:
+//
+//	func TestMyStore(t *testing.T) {
+//		policytest.Run(t, NewMyStore)
+//	}
+package policytest
+
+import (
+	"container/list"
+	"math/rand"
+	"testing"
+	"time"
+
+	cache "github.com/MeteorsLiu/LRUCache"
+)
+
+// ops is the number of random operations of every property check.
+const ops = 10000
+
+// Run checks the stores created by newStore against a model of their
+// behaviour, directly and as the index of a cache: the cache never
+// exceeds its capacity, evicts the least recently used keys, doesn't
+// resurrect evicted keys and honours TTLs.
+func Run(t *testing.T, newStore func() cache.Store) {
+	t.Run("Store", func(t *testing.T) { testStore(t, newStore) })
+	t.Run("Capacity", func(t *testing.T) { testCapacity(t, newStore) })
+	t.Run("TTL", func(t *testing.T) { testTTL(t, newStore) })
+}
+
+// key returns a random key of mixed types, from a small enough set
+// for keys to be hit again.
+func key(r *rand.Rand) cache.Key {
+	n := r.Intn(200)
+	switch n % 3 {
+	case 0:
+		return n
+	case 1:
+		return string(rune('a'+n%26)) + string(rune('a'+n/26))
+	}
+	return [2]int{n, -n}
+}
+
+func testStore(t *testing.T, newStore func() cache.Store) {
+	r := rand.New(rand.NewSource(1))
+	s := newStore()
+	model := make(map[cache.Key]*list.Element)
+	for i := 0; i < ops; i++ {
+		k := key(r)
+		switch r.Intn(3) {
+		case 0:
+			ele := &list.Element{Value: i}
+			s.Set(k, ele)
+			model[k] = ele
+		case 1:
+			s.Delete(k)
+			delete(model, k)
+		}
+		if got, ok := s.Get(k); ok != (model[k] != nil) || got != model[k] {
+			t.Fatalf("op %d: Get(%v) = %v, %v, want %v", i, k, got, ok, model[k])
+		}
+		if s.Len() != len(model) {
+			t.Fatalf("op %d: Len() = %d, want %d", i, s.Len(), len(model))
+		}
+	}
+	seen := 0
+	s.Range(func(k cache.Key, ele *list.Element) bool {
+		if model[k] != ele {
+			t.Fatalf("Range yielded %v for key %v, want %v", ele, k, model[k])
+		}
+		seen++
+		return true
+	})
+	if seen != len(model) {
+		t.Fatalf("Range yielded %d keys, want %d", seen, len(model))
+	}
+	calls := 0
+	s.Range(func(cache.Key, *list.Element) bool {
+		calls++
+		return false
+	})
+	if len(model) > 0 && calls != 1 {
+		t.Fatalf("Range called fn %d times after it returned false", calls)
+	}
+}
+
+// lru is a model of an LRU cache, most recently used key first.
+type lru struct {
+	capacity int
+	keys     []cache.Key
+}
+
+func (m *lru) find(k cache.Key) int {
+	for i, key := range m.keys {
+		if key == k {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *lru) touch(k cache.Key) bool {
+	i := m.find(k)
+	if i < 0 {
+		return false
+	}
+	copy(m.keys[1:i+1], m.keys[:i])
+	m.keys[0] = k
+	return true
+}
+
+func (m *lru) set(k cache.Key) {
+	if !m.touch(k) {
+		m.keys = append([]cache.Key{k}, m.keys...)
+	}
+	if len(m.keys) > m.capacity {
+		m.keys = m.keys[:m.capacity]
+	}
+}
+
+func (m *lru) remove(k cache.Key) {
+	if i := m.find(k); i >= 0 {
+		m.keys = append(m.keys[:i], m.keys[i+1:]...)
+	}
+}
+
+const capacity = 50
+
+func testCapacity(t *testing.T, newStore func() cache.Store) {
+	t.Run("MaxEntries", func(t *testing.T) {
+		checkCapacity(t, cache.New(capacity, cache.WithStore(newStore)))
+	})
+	t.Run("MaxCost", func(t *testing.T) {
+		// Every entry costs 1, so MaxCost bounds the number of entries.
+		checkCapacity(t, cache.New(0, cache.WithStore(newStore), cache.WithMaxCost(capacity)))
+	})
+}
+
+// checkCapacity checks c, holding up to capacity entries, against
+// the LRU model.
+func checkCapacity(t *testing.T, c *cache.Cache) {
+	r := rand.New(rand.NewSource(2))
+	model := &lru{capacity: capacity}
+	for i := 0; i < ops; i++ {
+		k := key(r)
+		switch r.Intn(4) {
+		case 0, 1:
+			v, ok := c.Get(k)
+			if want := model.touch(k); ok != want || ok && v != k {
+				t.Fatalf("op %d: Get(%v) = %v, %v, want hit %v", i, k, v, ok, want)
+			}
+		case 2:
+			c.Set(k, k)
+			model.set(k)
+		case 3:
+			c.Remove(k)
+			model.remove(k)
+		}
+		if n := c.Len(); n > capacity || n != len(model.keys) {
+			t.Fatalf("op %d: Len() = %d, want %d within %d", i, n, len(model.keys), capacity)
+		}
+	}
+}
+
+func testTTL(t *testing.T, newStore func() cache.Store) {
+	c := cache.New(0, cache.WithStore(newStore))
+	for i := 0; i < 100; i++ {
+		if i%2 == 0 {
+			c.SetWithExpire(i, i, time.Millisecond)
+		} else {
+			c.SetWithExpire(i, i, time.Hour)
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	for i := 0; i < 100; i++ {
+		if _, ok := c.Get(i); ok != (i%2 == 1) {
+			t.Fatalf("Get(%d) = %v after the short TTL", i, ok)
+		}
+	}
+	if n := c.Len(); n != 50 {
+		t.Fatalf("Len() = %d after the short TTL, want 50", n)
+	}
+}
@@ -0,0 +1,15 @@
+package policytest
+
+import (
+	"testing"
+
+	cache "github.com/MeteorsLiu/LRUCache"
+)
+
+func TestMapStore(t *testing.T) {
+	Run(t, cache.NewMapStore)
+}
+
+func TestRobinStore(t *testing.T) {
+	Run(t, cache.NewRobinStore)
+}
@@ -0,0 +1,263 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// workerPool runs the asynchronous work of a cache, like expiry
+// callbacks and background refreshes, on a fixed set of goroutines.
+type workerPool struct {
+	mu    sync.Mutex
+	cond  sync.Cond
+	tasks []func()
+	// limit bounds the queue, zero means no bound.
+	limit int
+	// active is the number of tasks running.
+	active  int
+	dropped uint64
+	closed  bool
+}
+
+// queuePerWorker bounds the queue of WithWorkers per worker.
+const queuePerWorker = 256
+
+// ErrQueueFull is returned by GetOrLoad to the callers waiting for a
+// background refresh the worker pool had no room for, see WithWorkers.
+var ErrQueueFull = errors.New("cache: worker queue full")
+
+// WithWorkers runs the background work of the cache on a pool of n
+// goroutines, so it never takes more than n goroutines however many
+// entries expire: the expiry callbacks of OnExpireSchedule, the
+// background refreshes of GetOrLoad, and the periodic work of the
+// janitor, the coarse clock, the memory watcher, WithSteadyState,
+// WithRollingStats and OnPressure, whose timers only submit it.
+// Up to 256 tasks per worker wait in a queue while every worker is
+// busy, see Stats.Queued. Tasks finding the queue full are dropped,
+// see Stats.Dropped: expiry callbacks aren't called, refreshes are
+// retried on a later lookup and periodic work on its next tick.
+//
+// The goroutines started by callers aren't part of the pool: the loads
+// of GetMultiOrLoad, up to 16 per call, and the loads bounded in time
+// by WithLoadTimeout, one per load until the loader returns, which
+// could starve the pool. Neither is the dispatcher of
+// WithOrderedEvictions, which delivers callbacks in order. After
+// Close, the workers finish the queued tasks and exit, later tasks
+// are dropped. It panics if n isn't positive.
+func WithWorkers(n int) Option {
+	if n <= 0 {
+		panic("cache: WithWorkers n must be positive")
+	}
+	return func(c *Cache) {
+		p := newWorkerPool(n * queuePerWorker)
+		for i := 0; i < n; i++ {
+			go p.work()
+		}
+		c.pool = p
+	}
+}
+
+func newWorkerPool(limit int) *workerPool {
+	p := &workerPool{limit: limit}
+	p.cond.L = &p.mu
+	return p
+}
+
+func (p *workerPool) work() {
+	for {
+		p.mu.Lock()
+		for len(p.tasks) == 0 && !p.closed {
+			p.cond.Wait()
+		}
+		if len(p.tasks) == 0 {
+			p.mu.Unlock()
+			return
+		}
+		task := p.tasks[0]
+		p.tasks[0] = nil
+		p.tasks = p.tasks[1:]
+		p.active++
+		p.mu.Unlock()
+		p.run(task)
+	}
+}
+
+func (p *workerPool) run(task func()) {
+	defer func() {
+		p.mu.Lock()
+		p.active--
+		p.mu.Unlock()
+	}()
+	task()
+}
+
+// submit queues task, reporting false if the pool is closed or its
+// queue is full.
+func (p *workerPool) submit(task func()) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || p.limit > 0 && len(p.tasks) >= p.limit {
+		p.dropped++
+		return false
+	}
+	p.tasks = append(p.tasks, task)
+	p.cond.Signal()
+	return true
+}
+
+func (p *workerPool) queued() int {
+	if p == nil {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.tasks)
+}
+
+// pending returns the number of tasks queued or running.
+func (p *workerPool) pending() int {
+	if p == nil {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.tasks) + p.active
+}
+
+// droppedTasks returns the number of tasks submit dropped.
+func (p *workerPool) droppedTasks() uint64 {
+	if p == nil {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dropped
+}
+
+func (p *workerPool) close() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.cond.Broadcast()
+}
+
+// spawn runs task asynchronously, on the worker pool if there is one.
+// It reports false if the pool dropped task.
+func (c *Cache) spawn(task func()) bool {
+	atomic.AddInt64(&c.spawned, 1)
+	run := func() {
+		defer atomic.AddInt64(&c.spawned, -1)
+		task()
+	}
+	if c.pool == nil {
+		go run()
+		return true
+	}
+	if !c.pool.submit(run) {
+		atomic.AddInt64(&c.spawned, -1)
+		return false
+	}
+	return true
+}
+
+// pendingTasks returns the number of tasks started by spawn and of
+// eviction callbacks waiting for the dispatcher, queued or running.
+// The periodic work isn't counted.
+func (c *Cache) pendingTasks() int {
+	return int(atomic.LoadInt64(&c.spawned)) + c.dispatcher.pending()
+}
+
+// periodic runs a task every interval, see every.
+type periodic struct {
+	mu       sync.Mutex
+	interval time.Duration
+	timer    *time.Timer
+	task     func()
+	pool     *workerPool
+	// running is set from when the timer fires to when the task
+	// returns, so runs never overlap.
+	running bool
+	// stopped is set by stop, closed by Close, after which the task
+	// isn't restarted.
+	stopped, closed bool
+}
+
+// every runs task every interval until Close is called. The timer only
+// submits task to the worker pool if there is one, and otherwise runs
+// it, so the periodic work of the cache takes no goroutine of its own.
+// The next run is scheduled when task returns.
+func (c *Cache) every(interval time.Duration, task func()) *periodic {
+	p := &periodic{interval: interval, task: task, pool: c.pool}
+	p.mu.Lock()
+	p.timer = time.AfterFunc(interval, p.fire)
+	p.mu.Unlock()
+	c.periodicsMu.Lock()
+	defer c.periodicsMu.Unlock()
+	select {
+	case <-c.done:
+		p.close()
+	default:
+		c.periodics = append(c.periodics, p)
+	}
+	return p
+}
+
+func (p *periodic) fire() {
+	p.mu.Lock()
+	if p.running || p.stopped || p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.running = true
+	p.mu.Unlock()
+	if p.pool == nil {
+		p.run()
+	} else if !p.pool.submit(p.run) {
+		// Skip a beat while the pool is busy.
+		p.schedule()
+	}
+}
+
+func (p *periodic) run() {
+	p.task()
+	p.schedule()
+}
+
+// schedule arms the timer for the next run.
+func (p *periodic) schedule() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running = false
+	if !p.stopped && !p.closed {
+		p.timer.Reset(p.interval)
+	}
+}
+
+// reset restarts the task every interval, unless the cache is closed.
+func (p *periodic) reset(interval time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.interval = interval
+	p.stopped = false
+	p.timer.Reset(interval)
+}
+
+// stop stops the task until reset is called.
+func (p *periodic) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = true
+	p.timer.Stop()
+}
+
+func (p *periodic) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.timer.Stop()
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkers(t *testing.T) {
+	c := New(0, WithWorkers(2))
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		c.SetWithExpire(i, i, time.Millisecond)
+		wg.Add(1)
+		c.OnExpireSchedule(i, func(Key, interface{}) {
+			<-release
+			wg.Done()
+		})
+	}
+	before := runtime.NumGoroutine()
+	time.Sleep(5 * time.Millisecond)
+	c.RemoveExpire()
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("%d goroutines after 50 expirations, %d before", n, before)
+	}
+	// Up to 2 tasks are running.
+	if q := c.Stats().Queued; q < 48 || q > 50 {
+		t.Fatalf("Stats().Queued = %d, want 48 to 50", q)
+	}
+	close(release)
+	wg.Wait()
+
+	// Tasks are dropped after Close.
+	c.Close()
+	if c.spawn(func() {}) || c.Stats().Dropped != 1 {
+		t.Fatalf("spawn after Close queued the task, %d dropped", c.Stats().Dropped)
+	}
+}
+
+func TestWorkersQueueFull(t *testing.T) {
+	c := New(0, WithWorkers(1))
+	defer c.Close()
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	c.spawn(func() {
+		close(started)
+		<-release
+	})
+	<-started
+	for i := 0; i < queuePerWorker; i++ {
+		if !c.spawn(func() {}) {
+			t.Fatalf("task %d dropped before the queue was full", i)
+		}
+	}
+	if c.spawn(func() {}) {
+		t.Fatal("the full queue took another task")
+	}
+	if s := c.Stats(); s.Queued != queuePerWorker || s.Dropped != 1 {
+		t.Fatalf("Queued = %d, Dropped = %d", s.Queued, s.Dropped)
+	}
+}
+
+func TestWorkersPeriodic(t *testing.T) {
+	before := runtime.NumGoroutine()
+	c := New(10, WithWorkers(2), WithJanitor(time.Millisecond),
+		WithCoarseClock(time.Millisecond), WithSteadyState(time.Millisecond), WithRollingStats())
+	var pressure sync.WaitGroup
+	pressure.Add(1)
+	var once sync.Once
+	c.OnPressure(PressureThresholds{Window: time.Millisecond, MissRatio: 0.5}, func(PressureInfo) {
+		once.Do(pressure.Done)
+	})
+	c.SetWithExpire("a", 1, time.Millisecond)
+	c.Get("b")
+	// Only the workers are long-lived, a timer firing holds a
+	// goroutine just long enough to submit its task.
+	n := 1 << 30
+	for i := 0; i < 20; i++ {
+		time.Sleep(time.Millisecond)
+		if g := runtime.NumGoroutine(); g < n {
+			n = g
+		}
+	}
+	if n > before+2 {
+		t.Fatalf("%d goroutines with 2 workers, %d before", n, before)
+	}
+	c.Get("b")
+	pressure.Wait()
+	if c.ApproxLen() != 0 {
+		t.Fatal("the janitor didn't remove the expired entry")
+	}
+	c.Close()
+}
+
+func TestWorkersZero(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("WithWorkers(0) didn't panic")
+		}
+	}()
+	New(10, WithWorkers(0))
+}
@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"strings"
+	"sync"
+)
+
+// PrefixCounts are the lookups of the keys sharing a prefix,
+// see WithPrefixStats.
+type PrefixCounts struct {
+	Hits, Misses uint64
+}
+
+// HitRatio returns the fraction of the lookups which hit, 0 without
+// lookups.
+func (p PrefixCounts) HitRatio() float64 {
+	if p.Hits+p.Misses == 0 {
+		return 0
+	}
+	return float64(p.Hits) / float64(p.Hits+p.Misses)
+}
+
+// prefixStats counts the lookups by key prefix. It has its own lock
+// since lookups served from a read snapshot don't take c.mu.
+type prefixStats struct {
+	prefix func(key string) (string, bool)
+	mu     sync.Mutex
+	counts map[string]*PrefixCounts
+}
+
+// OtherPrefix counts the keys WithPrefixStats finds too short for a
+// prefix.
+const OtherPrefix = "(other)"
+
+// WithPrefixStats breaks the hits and misses of string keys down by
+// their first depth sep-separated segments in Stats.Prefixes, so teams
+// sharing a cache see which traffic benefits from it and which only
+// pollutes it. With sep ":" and depth 1, "user:42:name" and "user:7"
+// are counted under "user". Keys with fewer segments are counted
+// together under OtherPrefix, keys of other types aren't counted.
+// depth is at least 1. The prefixes are rendered like keys, see
+// WithKeyRedactor.
+func WithPrefixStats(sep string, depth int) Option {
+	if depth < 1 {
+		depth = 1
+	}
+	return withPrefixes(func(key string) (string, bool) {
+		i := 0
+		for n := 0; n < depth; n++ {
+			j := strings.Index(key[i:], sep)
+			if j < 0 {
+				return OtherPrefix, true
+			}
+			i += j + len(sep)
+		}
+		return key[:i-len(sep)], true
+	})
+}
+
+// WithPrefixes is like WithPrefixStats, counting the lookups under the
+// longest of prefixes the key starts with. Keys matching none of them
+// aren't counted.
+func WithPrefixes(prefixes ...string) Option {
+	return withPrefixes(func(key string) (string, bool) {
+		match, ok := "", false
+		for _, p := range prefixes {
+			if strings.HasPrefix(key, p) && len(p) >= len(match) {
+				match, ok = p, true
+			}
+		}
+		return match, ok
+	})
+}
+
+func withPrefixes(prefix func(key string) (string, bool)) Option {
+	return func(c *Cache) {
+		c.prefixes = &prefixStats{prefix: prefix, counts: make(map[string]*PrefixCounts)}
+	}
+}
+
+// count records a lookup of key.
+func (s *prefixStats) count(key Key, hit bool) {
+	if s == nil {
+		return
+	}
+	k, ok := key.(string)
+	if !ok {
+		return
+	}
+	p, ok := s.prefix(k)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := s.counts[p]
+	if counts == nil {
+		counts = &PrefixCounts{}
+		s.counts[p] = counts
+	}
+	if hit {
+		counts.Hits++
+	} else {
+		counts.Misses++
+	}
+}
+
+// snapshot returns a copy of the counts with the prefixes rendered by
+// render, nil for a nil s.
+func (s *prefixStats) snapshot(render func(key Key) string) map[string]PrefixCounts {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[string]PrefixCounts, len(s.counts))
+	for p, n := range s.counts {
+		if p != OtherPrefix {
+			p = render(p)
+		}
+		// Distinct prefixes may be rendered alike.
+		sum := counts[p]
+		sum.Hits += n.Hits
+		sum.Misses += n.Misses
+		counts[p] = sum
+	}
+	return counts
+}
+
+// miss records a lookup of key which missed.
+func (c *Cache) miss(key Key) {
+	c.misses.add(1)
+	c.prefixes.count(key, false)
+}
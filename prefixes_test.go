@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPrefixStats(t *testing.T) {
+	c := New(10, WithPrefixStats(":", 2))
+	c.Set("user:42:name", 1)
+	c.Get("user:42:name")
+	c.Get("user:42:email")
+	c.Get("user:7")
+	c.Get("session")
+	c.Get(42)
+
+	want := map[string]PrefixCounts{
+		"user:42":   {Hits: 1, Misses: 1},
+		OtherPrefix: {Misses: 2},
+	}
+	if got := c.Stats().Prefixes; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Prefixes = %v, want %v", got, want)
+	}
+	if r := want["user:42"].HitRatio(); r != 0.5 {
+		t.Fatalf("HitRatio() = %v, want 0.5", r)
+	}
+}
+
+func TestPrefixes(t *testing.T) {
+	c := New(10, WithPrefixes("img/", "img/thumb/"))
+	c.Set("img/thumb/1", 1)
+	c.Get("img/thumb/1")
+	c.Get("img/2")
+	c.Get("doc/3")
+
+	want := map[string]PrefixCounts{
+		"img/thumb/": {Hits: 1},
+		"img/":       {Misses: 1},
+	}
+	if got := c.Stats().Prefixes; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Prefixes = %v, want %v", got, want)
+	}
+}
+
+func TestPrefixStatsRedacted(t *testing.T) {
+	c := New(10, WithPrefixStats(":", 1), WithKeyRedactor(func(key Key) string {
+		return strings.Repeat("*", len(key.(string)))
+	}))
+	c.Get("user:1")
+	c.Get("team:1")
+	c.Get("x")
+
+	want := map[string]PrefixCounts{
+		"****":      {Misses: 2},
+		OtherPrefix: {Misses: 1},
+	}
+	if got := c.Stats().Prefixes; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Prefixes = %v, want %v", got, want)
+	}
+}
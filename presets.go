@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"regexp"
+	"text/template"
+	"time"
+)
+
+// RegexpCache memoizes compiled regular expressions.
+type RegexpCache struct {
+	c *Cache
+}
+
+// NewRegexpCache returns a RegexpCache keeping up to max
+// compiled expressions.
+func NewRegexpCache(max int) *RegexpCache {
+	return &RegexpCache{c: New(max)}
+}
+
+// Compile is like regexp.Compile, returning the cached
+// *regexp.Regexp if expr was compiled before.
+func (rc *RegexpCache) Compile(expr string) (*regexp.Regexp, error) {
+	v, err := rc.c.GetOrLoad(expr, func(Key) (interface{}, error) {
+		return regexp.Compile(expr)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*regexp.Regexp), nil
+}
+
+// MustCompile is like Compile but panics if expr can't be parsed.
+func (rc *RegexpCache) MustCompile(expr string) *regexp.Regexp {
+	re, err := rc.Compile(expr)
+	if err != nil {
+		panic(`regexp: Compile(` + expr + `): ` + err.Error())
+	}
+	return re
+}
+
+// TemplateCache memoizes parsed text templates.
+type TemplateCache struct {
+	c *Cache
+}
+
+// NewTemplateCache returns a TemplateCache keeping up to max
+// parsed templates.
+func NewTemplateCache(max int) *TemplateCache {
+	return &TemplateCache{c: New(max)}
+}
+
+type templateKey struct {
+	name, text string
+}
+
+// Parse is like template.New(name).Parse(text), returning the cached
+// *template.Template if the same template was parsed before.
+// The returned template is shared, callers must not modify it.
+func (tc *TemplateCache) Parse(name, text string) (*template.Template, error) {
+	v, err := tc.c.GetOrLoad(templateKey{name, text}, func(Key) (interface{}, error) {
+		return template.New(name).Parse(text)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*template.Template), nil
+}
+
+// A TokenValidator validates a token, e.g. checks the signature of a
+// JWT, and returns its claims. An "exp" claim holding seconds since
+// the epoch bounds how long the result is cached.
+type TokenValidator func(token string) (claims map[string]interface{}, err error)
+
+// TokenCache memoizes token validation results keyed by the hash of
+// the token, so the tokens themselves aren't kept in memory.
+// Valid tokens are cached until they expire, invalid ones for a
+// shorter negative TTL so a flood of bad tokens doesn't reach the
+// validator.
+type TokenCache struct {
+	c        *Cache
+	validate TokenValidator
+}
+
+// tokenResult is the cached outcome of a validation.
+type tokenResult struct {
+	claims map[string]interface{}
+	err    error
+	exp    time.Time
+}
+
+// NewTokenCache returns a TokenCache of up to max results. Valid
+// results are cached for at most maxTTL and never past the token's
+// exp claim, invalid results for negativeTTL.
+func NewTokenCache(max int, validate TokenValidator, maxTTL, negativeTTL time.Duration) *TokenCache {
+	ttl := func(v interface{}) time.Duration {
+		r := v.(*tokenResult)
+		if r.err != nil {
+			return negativeTTL
+		}
+		if r.exp.IsZero() {
+			return maxTTL
+		}
+		if d := time.Until(r.exp); d > 0 {
+			return d
+		}
+		// Already expired, a zero TTL would mean never.
+		return time.Nanosecond
+	}
+	return &TokenCache{
+		c:        New(max, WithTTLFromValue(ttl), WithMaxTTL(maxTTL)),
+		validate: validate,
+	}
+}
+
+// Validate returns the claims of token, validating it only if its
+// result isn't cached.
+func (tc *TokenCache) Validate(token string) (map[string]interface{}, error) {
+	v, _ := tc.c.GetOrLoad(sha256.Sum256([]byte(token)), func(Key) (interface{}, error) {
+		claims, err := tc.validate(token)
+		return &tokenResult{claims: claims, err: err, exp: expClaim(claims)}, nil
+	})
+	r := v.(*tokenResult)
+	return r.claims, r.err
+}
+
+// expClaim returns the exp claim of claims, or zero if there is none.
+func expClaim(claims map[string]interface{}) time.Time {
+	var sec int64
+	switch exp := claims["exp"].(type) {
+	case float64:
+		sec = int64(exp)
+	case int64:
+		sec = exp
+	case int:
+		sec = int64(exp)
+	case json.Number:
+		n, err := exp.Int64()
+		if err != nil {
+			return time.Time{}
+		}
+		sec = n
+	default:
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
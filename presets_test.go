@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegexpCache(t *testing.T) {
+	rc := NewRegexpCache(10)
+	a := rc.MustCompile(`^a+$`)
+	if b := rc.MustCompile(`^a+$`); a != b {
+		t.Error("the compiled expression wasn't reused")
+	}
+	if _, err := rc.Compile(`(`); err == nil {
+		t.Error("Compile of an invalid expression succeeded")
+	}
+}
+
+func TestTemplateCache(t *testing.T) {
+	tc := NewTemplateCache(10)
+	a, err := tc.Parse("t", "hello {{.}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := tc.Parse("t", "hello {{.}}"); a != b {
+		t.Error("the parsed template wasn't reused")
+	}
+	var sb strings.Builder
+	if err := a.Execute(&sb, "world"); err != nil || sb.String() != "hello world" {
+		t.Errorf("Execute = %q, %v", sb.String(), err)
+	}
+}
+
+func TestTokenCache(t *testing.T) {
+	calls := 0
+	errInvalid := errors.New("invalid token")
+	tc := NewTokenCache(10, func(token string) (map[string]interface{}, error) {
+		calls++
+		switch token {
+		case "good":
+			return map[string]interface{}{"sub": "gopher", "exp": float64(time.Now().Add(time.Hour).Unix())}, nil
+		case "short":
+			return map[string]interface{}{"exp": float64(time.Now().Unix())}, nil
+		}
+		return nil, errInvalid
+	}, time.Minute, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		claims, err := tc.Validate("good")
+		if err != nil || claims["sub"] != "gopher" {
+			t.Fatalf("Validate(good) = %v, %v", claims, err)
+		}
+		if _, err := tc.Validate("bad"); err != errInvalid {
+			t.Fatalf("Validate(bad) = %v, want errInvalid", err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("validator called %d times, want 2", calls)
+	}
+
+	tc.Validate("short")
+	time.Sleep(time.Millisecond)
+	tc.Validate("short")
+	if calls != 4 {
+		t.Fatal("an expired token's result was cached")
+	}
+}
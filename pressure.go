@@ -0,0 +1,66 @@
+package cache
+
+import "time"
+
+// PressureThresholds are the limits past which OnPressure reports
+// the cache under pressure. A zero threshold isn't checked.
+type PressureThresholds struct {
+	// Window is the period the rates are measured over.
+	Window time.Duration
+	// EvictionRate is in evictions per second.
+	EvictionRate float64
+	// MissRatio is the share of lookups which missed, from 0 to 1.
+	MissRatio float64
+}
+
+// PressureInfo describes the activity of a cache over a window
+// in which it crossed a PressureThresholds.
+type PressureInfo struct {
+	Window       time.Duration
+	Evictions    uint64
+	Hits, Misses uint64
+	EvictionRate float64
+	MissRatio    float64
+}
+
+// pressureSampler computes the PressureInfo of successive windows.
+type pressureSampler struct {
+	thresholds PressureThresholds
+	last       Stats
+}
+
+// sample returns the activity since the previous sample, and whether
+// it crossed the thresholds.
+func (p *pressureSampler) sample(s Stats) (PressureInfo, bool) {
+	info := PressureInfo{
+		Window:    p.thresholds.Window,
+		Evictions: s.Evictions - p.last.Evictions,
+		Hits:      s.Hits - p.last.Hits,
+		Misses:    s.Misses - p.last.Misses,
+	}
+	p.last = s
+	info.EvictionRate = float64(info.Evictions) / info.Window.Seconds()
+	if lookups := info.Hits + info.Misses; lookups > 0 {
+		info.MissRatio = float64(info.Misses) / float64(lookups)
+	}
+	t := p.thresholds
+	return info, t.EvictionRate > 0 && info.EvictionRate >= t.EvictionRate ||
+		t.MissRatio > 0 && info.MissRatio >= t.MissRatio
+}
+
+// OnPressure calls fn at the end of every window
+// of t.Window in which the eviction rate or the miss ratio crossed its
+// threshold, so applications can shed load, widen TTLs or alert before
+// the cache becomes ineffective. It runs until Close is called.
+// It panics if t.Window isn't positive.
+func (c *Cache) OnPressure(t PressureThresholds, fn func(p PressureInfo)) {
+	if t.Window <= 0 {
+		panic("cache: OnPressure window must be positive")
+	}
+	p := &pressureSampler{thresholds: t, last: c.Stats()}
+	c.every(t.Window, func() {
+		if info, ok := p.sample(c.Stats()); ok {
+			fn(info)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPressureSampler(t *testing.T) {
+	p := &pressureSampler{thresholds: PressureThresholds{Window: time.Second, EvictionRate: 100, MissRatio: 0.5}}
+	if _, ok := p.sample(Stats{Hits: 90, Misses: 10, Evictions: 10}); ok {
+		t.Fatal("reported pressure under the thresholds")
+	}
+	info, ok := p.sample(Stats{Hits: 100, Misses: 20, Evictions: 20})
+	if !ok || info.Misses != 10 || info.MissRatio != 0.5 {
+		t.Fatalf("sample = %+v, %v", info, ok)
+	}
+	info, ok = p.sample(Stats{Hits: 200, Misses: 20, Evictions: 220})
+	if !ok || info.EvictionRate != 200 || info.MissRatio != 0 {
+		t.Fatalf("sample = %+v, %v", info, ok)
+	}
+}
+
+func TestOnPressure(t *testing.T) {
+	c := New(1)
+	defer c.Close()
+	reported := make(chan PressureInfo, 1)
+	c.OnPressure(PressureThresholds{Window: 5 * time.Millisecond, MissRatio: 0.9}, func(p PressureInfo) {
+		select {
+		case reported <- p:
+		default:
+		}
+	})
+	for i := 0; i < 100; i++ {
+		c.Get(i)
+	}
+	select {
+	case p := <-reported:
+		if p.MissRatio != 1 {
+			t.Fatalf("MissRatio = %v", p.MissRatio)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pressure wasn't reported")
+	}
+}
+
+func TestOnPressureZeroWindow(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("OnPressure with a zero window didn't panic")
+		}
+	}()
+	New(10).OnPressure(PressureThresholds{}, func(PressureInfo) {})
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// provenanceDepth is the number of call sites reported by Info.
+const provenanceDepth = 4
+
+// callers is the stack recorded by WithProvenance, deep enough to reach
+// the caller through the methods of Cache.
+type callers struct {
+	pcs [provenanceDepth + 8]uintptr
+	n   int
+}
+
+// methodPrefix prefixes the methods of Cache in stack frames.
+var methodPrefix = reflect.TypeOf(Cache{}).PkgPath() + ".(*Cache)."
+
+// WithProvenance records the call site which last set every key, for
+// answering "who put this value here?" in production, see Info.
+// Recording walks the stack on every write, so it's meant for debugging.
+func WithProvenance() Option {
+	return func(c *Cache) {
+		c.provenance = true
+	}
+}
+
+// recordCallers records the stack of the write setting e.
+// The caller must hold c.mu.
+func (c *Cache) recordCallers(e *entry) {
+	if !c.provenance {
+		return
+	}
+	cs := new(callers)
+	// Skip runtime.Callers, recordCallers and its caller.
+	cs.n = runtime.Callers(3, cs.pcs[:])
+	e.setBy = cs
+}
+
+// sites formats the call sites outside of the methods of Cache,
+// innermost first.
+func (cs *callers) sites() []string {
+	if cs == nil {
+		return nil
+	}
+	var sites []string
+	frames := runtime.CallersFrames(cs.pcs[:cs.n])
+	for len(sites) < provenanceDepth {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, methodPrefix) {
+			sites = append(sites, fmt.Sprintf("%s %s:%d", f.Function, f.File, f.Line))
+		}
+		if !more {
+			break
+		}
+	}
+	return sites
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotInteger is returned by Increment when the value of the key
+// isn't an int64.
+var ErrNotInteger = errors.New("cache: value is not an int64")
+
+// Increment atomically adds delta to the int64 stored for key and
+// returns the new value. A missing or expired key starts from zero and
+// expires after ttl, which makes the counter reset once per window;
+// incrementing an existing key keeps its expiration.
+func (c *Cache) Increment(key Key, delta int64, ttl time.Duration) (int64, error) {
+	var n int64
+	err := c.modifyWithExpire(key, c.expireAt(ttl), func(v interface{}, ok bool) (interface{}, error) {
+		if !ok {
+			n = delta
+			return n, nil
+		}
+		cur, isInt := v.(int64)
+		if !isInt {
+			return nil, ErrNotInteger
+		}
+		n = cur + delta
+		return n, nil
+	})
+	return n, err
+}
+
+// RateLimiter is a memory-bounded sliding window rate limiter keeping
+// its counters in a cache: the least recently active keys are evicted
+// when it's full.
+type RateLimiter struct {
+	c      *Cache
+	limit  int64
+	window time.Duration
+}
+
+// windowKey is the counter of key in a window.
+type windowKey struct {
+	key    Key
+	window int64
+}
+
+// NewRateLimiter returns a RateLimiter allowing limit events per window
+// for each of up to maxKeys keys. It panics if maxKeys or window isn't
+// positive.
+func NewRateLimiter(maxKeys int, limit int64, window time.Duration) *RateLimiter {
+	if maxKeys <= 0 {
+		panic("cache: NewRateLimiter maxKeys must be positive")
+	}
+	if window <= 0 {
+		panic("cache: NewRateLimiter window must be positive")
+	}
+	// Each key has a counter for the current and the previous window.
+	return &RateLimiter{c: New(2 * maxKeys), limit: limit, window: window}
+}
+
+// Allow records an event for key and reports whether it's within the
+// limit. The rate is estimated over a sliding window, weighting the
+// count of the previous window by how much of it still overlaps.
+func (rl *RateLimiter) Allow(key Key) bool {
+	now := time.Now().UnixNano()
+	w := int64(rl.window)
+	cur := now / w
+	elapsed := float64(now%w) / float64(w)
+
+	prev, _ := rl.c.Peek(windowKey{key, cur - 1})
+	prevCount, _ := prev.(int64)
+	// The counter outlives its window to weigh the next one.
+	n, err := rl.c.Increment(windowKey{key, cur}, 1, 2*rl.window)
+	if err != nil {
+		return false
+	}
+	return float64(prevCount)*(1-elapsed)+float64(n) <= float64(rl.limit)
+}
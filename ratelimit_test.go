@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIncrement(t *testing.T) {
+	ce := New(10)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ce.Increment("n", 2, time.Hour)
+		}()
+	}
+	wg.Wait()
+	if n, err := ce.Increment("n", 0, time.Hour); n != 20 || err != nil {
+		t.Fatalf("Increment = %d, %v, want 20", n, err)
+	}
+
+	ce.Increment("window", 1, 5*time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	if n, _ := ce.Increment("window", 1, 5*time.Millisecond); n != 1 {
+		t.Fatalf("counter = %d after its window, want 1", n)
+	}
+
+	ce.Set("s", "x")
+	if _, err := ce.Increment("s", 1, 0); err != ErrNotInteger {
+		t.Fatalf("Increment of a string = %v, want ErrNotInteger", err)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	rl := NewRateLimiter(10, 3, time.Hour)
+	for i := 0; i < 3; i++ {
+		if !rl.Allow("a") {
+			t.Fatalf("event %d was limited", i)
+		}
+	}
+	if rl.Allow("a") {
+		t.Fatal("event over the limit was allowed")
+	}
+	if !rl.Allow("b") {
+		t.Fatal("keys share a limit")
+	}
+}
+
+func TestRateLimiterInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		maxKeys int
+		window  time.Duration
+	}{
+		{0, time.Second},
+		{-1, time.Second},
+		{10, 0},
+		{10, -time.Second},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewRateLimiter(%d, 1, %v) didn't panic", tc.maxKeys, tc.window)
+				}
+			}()
+			NewRateLimiter(tc.maxKeys, 1, tc.window)
+		}()
+	}
+}
@@ -0,0 +1,22 @@
+package cache
+
+import "fmt"
+
+// WithKeyRedactor makes the cache render keys with redact wherever it
+// externalizes them as text: dumps, error messages and diagnostics, so
+// raw identifiers like emails never leak into logs or observability
+// systems. Keys handed to callbacks and returned by methods, like
+// Info, are the keys themselves.
+func WithKeyRedactor(redact func(key Key) string) Option {
+	return func(c *Cache) {
+		c.redactKey = redact
+	}
+}
+
+// keyString renders key as text.
+func (c *Cache) keyString(key Key) string {
+	if c.redactKey != nil {
+		return c.redactKey(key)
+	}
+	return fmt.Sprint(key)
+}
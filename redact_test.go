@@ -0,0 +1,29 @@
+package cache
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestKeyRedactor(t *testing.T) {
+	var msg string
+	c := New(10,
+		WithKeyRedactor(func(Key) string { return "<redacted>" }),
+		WithTypeGuard(false, func(err *TypeMismatchError) { msg = err.Error() }))
+	c.Set("alice@example.com", 1)
+	c.Set("alice@example.com", "one")
+
+	var buf bytes.Buffer
+	if err := c.DumpCSV(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, out := range []string{buf.String(), msg} {
+		if strings.Contains(out, "alice") || !strings.Contains(out, "<redacted>") {
+			t.Fatalf("key not redacted in %q", out)
+		}
+	}
+	if info, _ := c.Info("alice@example.com"); info.Key != "alice@example.com" {
+		t.Fatalf("Info returned key %v", info.Key)
+	}
+}
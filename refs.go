@@ -0,0 +1,95 @@
+package cache
+
+import "context"
+
+// Acquire looks up a key's value like Get and holds a reference to it
+// until release is called. If the entry is removed in the meantime,
+// OnEvicted is only called once every reference has been released, so
+// values holding resources like pooled connections aren't closed while
+// still in use. release must be called exactly once when ok is true;
+// extra calls are ignored.
+func (c *Cache) Acquire(key Key) (value interface{}, release func(), ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil {
+		return
+	}
+	ele, hit := c.store.Get(key)
+	if !hit {
+		c.miss(key)
+		return
+	}
+	e := ele.Value.(*entry)
+	now := c.now()
+	if e.expired(now) {
+		c.expire(ele)
+		c.miss(key)
+		return
+	}
+	c.touch(ele)
+	c.hit(e, now)
+	e.refs++
+	value = e.value
+	released := false
+	release = func() {
+		c.mu.Lock()
+		if released {
+			c.mu.Unlock()
+			return
+		}
+		released = true
+		e.refs--
+		last := e.refs == 0 && e.removed
+		if e.refs == 0 {
+			c.freed()
+		}
+		c.mu.Unlock()
+		if last {
+			c.evicted(context.Background(), e.key, value)
+		}
+	}
+	return value, release, true
+}
+
+// finalize marks e as removed from the cache and calls OnEvicted,
+// unless readers still hold it. The caller must hold c.mu.
+func (c *Cache) finalize(e *entry) {
+	e.removed = true
+	c.freed()
+	if e.refs == 0 {
+		ctx := c.evictCtx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		c.evicted(ctx, e.key, e.value)
+	}
+}
+
+// WithOnEvictedContext sets a callback called like OnEvicted, in
+// addition to it, with a context: the one passed to ClearContext for
+// the entries it purges and context.Background() otherwise.
+func WithOnEvictedContext(fn func(ctx context.Context, key Key, value interface{})) Option {
+	return func(c *Cache) {
+		c.onEvictedCtx = fn
+	}
+}
+
+// evicted calls the eviction callbacks, or queues them on the
+// dispatcher set by WithOrderedEvictions.
+func (c *Cache) evicted(ctx context.Context, key Key, value interface{}) {
+	if c.dispatcher != nil && (c.OnEvicted != nil || c.onEvictedCtx != nil) {
+		if c.dispatcher.submit(func() { c.notifyEvicted(ctx, key, value) }) {
+			return
+		}
+	}
+	c.notifyEvicted(ctx, key, value)
+}
+
+func (c *Cache) notifyEvicted(ctx context.Context, key Key, value interface{}) {
+	if c.OnEvicted != nil {
+		c.OnEvicted(key, value)
+	}
+	if c.onEvictedCtx != nil {
+		c.onEvictedCtx(ctx, key, value)
+	}
+}
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAcquire(t *testing.T) {
+	var evicted []Key
+	ce := New(0)
+	ce.OnEvicted = func(key Key, _ interface{}) {
+		evicted = append(evicted, key)
+	}
+	ce.Set("conn", 1)
+	v, release, ok := ce.Acquire("conn")
+	if !ok || v != 1 {
+		t.Fatalf("Acquire = %v, %v", v, ok)
+	}
+	_, release2, _ := ce.Acquire("conn")
+
+	ce.Remove("conn")
+	if len(evicted) != 0 {
+		t.Fatal("OnEvicted ran while the value was acquired")
+	}
+	release()
+	release()
+	if len(evicted) != 0 {
+		t.Fatal("OnEvicted ran before the last release")
+	}
+	release2()
+	if len(evicted) != 1 {
+		t.Fatalf("OnEvicted ran %d times after the last release, want 1", len(evicted))
+	}
+
+	ce.Set("idle", 2)
+	_, release, _ = ce.Acquire("idle")
+	release()
+	ce.Remove("idle")
+	if len(evicted) != 2 {
+		t.Fatal("OnEvicted didn't run for a released value")
+	}
+	if _, _, ok := ce.Acquire("idle"); ok {
+		t.Fatal("Acquire of a removed key succeeded")
+	}
+}
+
+func TestOnEvictedContext(t *testing.T) {
+	type ctxKey struct{}
+	var got []interface{}
+	c := New(2, WithOnEvictedContext(func(ctx context.Context, key Key, value interface{}) {
+		got = append(got, ctx.Value(ctxKey{}))
+	}))
+	c.Set(1, 1)
+	c.Set(2, 2)
+	c.Set(3, 3)
+	ctx := context.WithValue(context.Background(), ctxKey{}, "shutdown")
+	c.ClearContext(ctx)
+	if len(got) != 3 || got[0] != nil || got[1] != "shutdown" || got[2] != "shutdown" {
+		t.Fatalf("eviction contexts = %v", got)
+	}
+}
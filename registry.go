@@ -0,0 +1,53 @@
+package cache
+
+import "sync"
+
+// registry holds the caches registered by name.
+var registry struct {
+	mu     sync.RWMutex
+	caches map[string]*Cache
+}
+
+// Register makes c discoverable by name through Lookup and All, e.g.
+// by metrics exporters and admin endpoints. Like sql.Register, it
+// panics if name is already registered or c is nil.
+func Register(name string, c *Cache) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if c == nil {
+		panic("cache: Register cache is nil")
+	}
+	if _, dup := registry.caches[name]; dup {
+		panic("cache: Register called twice for " + name)
+	}
+	if registry.caches == nil {
+		registry.caches = make(map[string]*Cache)
+	}
+	registry.caches[name] = c
+}
+
+// Unregister removes the cache registered by name, if any.
+func Unregister(name string) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	delete(registry.caches, name)
+}
+
+// Lookup returns the cache registered by name.
+func Lookup(name string) (*Cache, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	c, ok := registry.caches[name]
+	return c, ok
+}
+
+// All returns the registered caches by name.
+func All() map[string]*Cache {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	all := make(map[string]*Cache, len(registry.caches))
+	for name, c := range registry.caches {
+		all[name] = c
+	}
+	return all
+}
@@ -0,0 +1,27 @@
+package cache
+
+import "testing"
+
+func TestRegistry(t *testing.T) {
+	users := New(10)
+	Register("users", users)
+	defer Unregister("users")
+	if c, ok := Lookup("users"); !ok || c != users {
+		t.Fatal("Lookup didn't return the registered cache")
+	}
+	if all := All(); all["users"] != users {
+		t.Fatalf("All() = %v", all)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("registering a name twice didn't panic")
+			}
+		}()
+		Register("users", New(10))
+	}()
+	Unregister("users")
+	if _, ok := Lookup("users"); ok {
+		t.Fatal("Lookup found an unregistered cache")
+	}
+}
@@ -0,0 +1,127 @@
+// Package remote shares a cache between the processes of a host: one
+// process hosts it with Serve and the others attach with Dial, e.g.
+// over a unix domain socket, so short-lived workers share one warm
+// cache. Keys and values travel gob encoded, so their concrete types
+// other than the basic ones must be registered with gob.Register.
+package remote
+
+import (
+	"net"
+	"net/rpc"
+	"time"
+
+	cache "github.com/MeteorsLiu/LRUCache"
+)
+
+// Serve serves c to the clients connecting to l until accepting
+// a connection fails.
+func Serve(l net.Listener, c *cache.Cache) error {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("Cache", &Service{c: c}); err != nil {
+		return err
+	}
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go srv.ServeConn(conn)
+	}
+}
+
+// Service is the RPC service exposing a cache. It's exported for
+// net/rpc only, use Serve and Dial.
+type Service struct {
+	c *cache.Cache
+}
+
+// SetArgs are the arguments of Service.Set.
+type SetArgs struct {
+	Key   cache.Key
+	Value interface{}
+	// TTL is zero for Set.
+	TTL time.Duration
+}
+
+// GetReply is the reply of Service.Get.
+type GetReply struct {
+	Value interface{}
+	Ok    bool
+}
+
+// Get looks up a key's value.
+func (s *Service) Get(key *cache.Key, reply *GetReply) error {
+	reply.Value, reply.Ok = s.c.Get(*key)
+	return nil
+}
+
+// Set adds a value.
+func (s *Service) Set(args *SetArgs, _ *struct{}) error {
+	if args.TTL > 0 {
+		s.c.SetWithExpire(args.Key, args.Value, args.TTL)
+	} else {
+		s.c.Set(args.Key, args.Value)
+	}
+	return nil
+}
+
+// Remove removes a key.
+func (s *Service) Remove(key *cache.Key, _ *struct{}) error {
+	s.c.Remove(*key)
+	return nil
+}
+
+// Client is a cache.Cacher served by another process. Like a local cache it
+// doesn't fail: a lookup which fails is a miss and a write which fails
+// is dropped, after being reported to OnError.
+type Client struct {
+	rpc *rpc.Client
+	// OnError, if set, is called with the errors of the calls.
+	OnError func(err error)
+}
+
+// Dial connects to the cache served at address.
+func Dial(network, address string) (*Client, error) {
+	c, err := rpc.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{rpc: c}, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
+func (c *Client) call(method string, args, reply interface{}) bool {
+	err := c.rpc.Call(method, args, reply)
+	if err != nil && c.OnError != nil {
+		c.OnError(err)
+	}
+	return err == nil
+}
+
+// Get looks up a key's value from the cache.
+func (c *Client) Get(key cache.Key) (value interface{}, ok bool) {
+	var reply GetReply
+	if !c.call("Cache.Get", &key, &reply) {
+		return nil, false
+	}
+	return reply.Value, reply.Ok
+}
+
+// Set adds a value to the cache.
+func (c *Client) Set(key cache.Key, value interface{}) {
+	c.call("Cache.Set", &SetArgs{Key: key, Value: value}, &struct{}{})
+}
+
+// SetWithExpire adds a value to the cache which expires after ttl.
+func (c *Client) SetWithExpire(key cache.Key, value interface{}, ttl time.Duration) {
+	c.call("Cache.Set", &SetArgs{Key: key, Value: value, TTL: ttl}, &struct{}{})
+}
+
+// Remove removes key from the cache.
+func (c *Client) Remove(key cache.Key) {
+	c.call("Cache.Remove", &key, &struct{}{})
+}
@@ -0,0 +1,48 @@
+package remote
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cache "github.com/MeteorsLiu/LRUCache"
+)
+
+var _ cache.Cacher = (*Client)(nil)
+
+func TestRemote(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skip("unix sockets unavailable:", err)
+	}
+	defer l.Close()
+	c := cache.New(10)
+	go Serve(l, c)
+
+	client, err := Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.Set("a", 1)
+	client.SetWithExpire("b", "two", time.Hour)
+	if v, ok := client.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v", v, ok)
+	}
+	if v, ok := c.Get("b"); !ok || v != "two" {
+		t.Fatalf("served cache Get(b) = %v, %v", v, ok)
+	}
+	client.Remove("a")
+	if _, ok := client.Get("a"); ok {
+		t.Fatal("Get after Remove succeeded")
+	}
+
+	var errs int
+	client.OnError = func(error) { errs++ }
+	client.Close()
+	if _, ok := client.Get("b"); ok || errs != 1 {
+		t.Fatalf("Get on a closed client = %v with %d errors", ok, errs)
+	}
+}
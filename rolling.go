@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// RollingWindows are the windows of Stats.Windows.
+var RollingWindows = [...]time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+
+// rollingInterval is how often the counters are sampled.
+const rollingInterval = 5 * time.Second
+
+// rollingSamples is enough samples to cover the longest window.
+const rollingSamples = int(15*time.Minute/rollingInterval) + 1
+
+// WindowStats are the lookup rates over the last Window.
+type WindowStats struct {
+	Window time.Duration
+	// HitsPerSec and MissesPerSec are the average rates over the
+	// window, or over the time since sampling started if it's shorter.
+	HitsPerSec, MissesPerSec float64
+	// MissRatio is the fraction of the lookups in the window which
+	// missed, 0 without lookups.
+	MissRatio float64
+}
+
+// rollingStats keeps a ring buffer of counter samples.
+type rollingStats struct {
+	mu      sync.Mutex
+	samples [rollingSamples]counterSample
+	// next is the index of the next sample and n the number of samples.
+	next, n int
+}
+
+type counterSample struct {
+	at           int64
+	hits, misses uint64
+}
+
+// WithRollingStats samples the hit and miss
+// counters every 5 seconds, reporting their rates over the last 1, 5
+// and 15 minutes in Stats.Windows, so dashboards don't have to diff
+// the raw counters. The sampling runs until Close is called.
+func WithRollingStats() Option {
+	return func(c *Cache) {
+		c.rolling = &rollingStats{}
+	}
+}
+
+func (c *Cache) sampleRolling() {
+	c.rolling.sample(c.now(), c.hits.load(), c.misses.load())
+}
+
+func (r *rollingStats) sample(now int64, hits, misses uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.samples[r.next] = counterSample{now, hits, misses}
+	r.next = (r.next + 1) % rollingSamples
+	if r.n < rollingSamples {
+		r.n++
+	}
+}
+
+// windows returns the rates over RollingWindows up to now, when the
+// counters are hits and misses, nil for a nil r.
+func (r *rollingStats) windows(now int64, hits, misses uint64) []WindowStats {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ws := make([]WindowStats, len(RollingWindows))
+	for i, d := range RollingWindows {
+		ws[i].Window = d
+		// Find the oldest sample within the window.
+		var from *counterSample
+		for j := 1; j <= r.n; j++ {
+			s := &r.samples[(r.next-j+rollingSamples)%rollingSamples]
+			if now-s.at > int64(d) {
+				break
+			}
+			from = s
+		}
+		if from == nil || now <= from.at {
+			continue
+		}
+		secs := time.Duration(now - from.at).Seconds()
+		h, m := hits-from.hits, misses-from.misses
+		ws[i].HitsPerSec = float64(h) / secs
+		ws[i].MissesPerSec = float64(m) / secs
+		if h+m > 0 {
+			ws[i].MissRatio = float64(m) / float64(h+m)
+		}
+	}
+	return ws
+}
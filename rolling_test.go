@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRollingStats(t *testing.T) {
+	var r rollingStats
+	sec := int64(time.Second)
+	// 100 hits/s for 20 minutes, misses only in the last 2 minutes.
+	var hits, misses uint64
+	for at := int64(0); at <= 20*60; at += 5 {
+		r.sample(at*sec, hits, misses)
+		hits += 500
+		if at >= 18*60 {
+			misses += 500
+		}
+	}
+	now := int64(20*60+5) * sec
+	ws := r.windows(now, hits, misses)
+	if len(ws) != 3 {
+		t.Fatalf("got %d windows, want 3", len(ws))
+	}
+	for i, w := range ws {
+		if w.Window != RollingWindows[i] || w.HitsPerSec != 100 {
+			t.Errorf("window %v: %v hits/s, want 100", w.Window, w.HitsPerSec)
+		}
+	}
+	if ws[0].MissRatio != 0.5 {
+		t.Errorf("1m miss ratio = %v, want 0.5", ws[0].MissRatio)
+	}
+	if r := ws[2].MissRatio; r <= 0.1 || r >= 0.2 {
+		t.Errorf("15m miss ratio = %v, want about 2/17", r)
+	}
+}
+
+func TestRollingStatsStart(t *testing.T) {
+	c := New(10, WithRollingStats())
+	defer c.Close()
+	if ws := New(10).Stats().Windows; ws != nil {
+		t.Fatalf("Windows = %v without WithRollingStats", ws)
+	}
+	c.Get("a")
+	// The first sample is taken asynchronously.
+	deadline := time.Now().Add(time.Second)
+	for {
+		w := c.Stats().Windows[0]
+		if w.MissesPerSec > 0 && w.MissRatio == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("1m window = %+v after a miss", w)
+		}
+		time.Sleep(time.Millisecond)
+		c.Get("a")
+	}
+}
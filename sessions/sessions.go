@@ -0,0 +1,189 @@
+// Package sessions stores HTTP sessions in an LRU cache.
+//
+// It follows the gorilla/sessions Store design: sessions are looked up
+// by a cookie holding their ID, their values live server side in the
+// cache and expire after a period of inactivity.
+package sessions
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"net/http"
+	"time"
+
+	cache "github.com/MeteorsLiu/LRUCache"
+)
+
+// Options are the attributes of session cookies.
+// A MaxAge below zero deletes the session when it's saved.
+type Options struct {
+	Path     string
+	Domain   string
+	MaxAge   int
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+}
+
+// Session is the data of one user session.
+type Session struct {
+	// ID is empty until a new session is saved.
+	ID      string
+	Values  map[interface{}]interface{}
+	Options *Options
+	// IsNew reports whether the session wasn't found in the store.
+	IsNew bool
+
+	store *Store
+	name  string
+}
+
+// Name returns the name of the session, which is its cookie name.
+func (s *Session) Name() string {
+	return s.name
+}
+
+// Save saves the session in the store it came from.
+func (s *Session) Save(r *http.Request, w http.ResponseWriter) error {
+	return s.store.Save(r, w, s)
+}
+
+// A Codec serializes session values. Values are stored encoded so
+// handlers can't mutate a cached session without saving it.
+type Codec interface {
+	Encode(values map[interface{}]interface{}) ([]byte, error)
+	Decode(data []byte) (map[interface{}]interface{}, error)
+}
+
+// GobCodec encodes values with encoding/gob. Like with gorilla/sessions,
+// custom value types must be registered with gob.Register.
+type GobCodec struct{}
+
+// Encode implements Codec.
+func (GobCodec) Encode(values map[interface{}]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode implements Codec.
+func (GobCodec) Decode(data []byte) (map[interface{}]interface{}, error) {
+	var values map[interface{}]interface{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// sessionKey keeps session IDs apart from other keys in the cache.
+type sessionKey string
+
+// Store keeps sessions in a cache with a sliding expiration: every time
+// a session is loaded or saved its TTL starts over.
+type Store struct {
+	// Options are the default cookie attributes of new sessions.
+	Options *Options
+
+	c     *cache.Cache
+	ttl   time.Duration
+	codec Codec
+}
+
+// NewStore returns a Store keeping sessions in c, which expire after
+// being unused for ttl. A nil codec means GobCodec.
+func NewStore(c *cache.Cache, ttl time.Duration, codec Codec) *Store {
+	if codec == nil {
+		codec = GobCodec{}
+	}
+	return &Store{
+		Options: &Options{Path: "/", MaxAge: int(ttl / time.Second), HttpOnly: true},
+		c:       c,
+		ttl:     ttl,
+		codec:   codec,
+	}
+}
+
+// Get returns the session name of the request, see New.
+func (st *Store) Get(r *http.Request, name string) (*Session, error) {
+	return st.New(r, name)
+}
+
+// New returns the session name of the request, loading it from the
+// cache if the request carries the cookie of a live session, and
+// returning a new session otherwise.
+func (st *Store) New(r *http.Request, name string) (*Session, error) {
+	opts := *st.Options
+	s := &Session{
+		Values:  make(map[interface{}]interface{}),
+		Options: &opts,
+		IsNew:   true,
+		store:   st,
+		name:    name,
+	}
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return s, nil
+	}
+	v, ok := st.c.Get(sessionKey(cookie.Value))
+	if !ok {
+		return s, nil
+	}
+	values, err := st.codec.Decode(v.([]byte))
+	if err != nil {
+		return s, err
+	}
+	// Slide the expiration.
+	st.c.SetWithExpire(sessionKey(cookie.Value), v, st.ttl)
+	s.ID, s.Values, s.IsNew = cookie.Value, values, false
+	return s, nil
+}
+
+// Save stores the session and sets its cookie on w.
+func (st *Store) Save(r *http.Request, w http.ResponseWriter, s *Session) error {
+	if s.Options.MaxAge < 0 {
+		if s.ID != "" {
+			st.c.Remove(sessionKey(s.ID))
+		}
+		http.SetCookie(w, st.cookie(s, ""))
+		return nil
+	}
+	if s.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		s.ID = id
+	}
+	data, err := st.codec.Encode(s.Values)
+	if err != nil {
+		return err
+	}
+	st.c.SetWithExpire(sessionKey(s.ID), data, st.ttl)
+	http.SetCookie(w, st.cookie(s, s.ID))
+	return nil
+}
+
+func (st *Store) cookie(s *Session, value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     s.name,
+		Value:    value,
+		Path:     s.Options.Path,
+		Domain:   s.Options.Domain,
+		MaxAge:   s.Options.MaxAge,
+		Secure:   s.Options.Secure,
+		HttpOnly: s.Options.HttpOnly,
+		SameSite: s.Options.SameSite,
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,70 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cache "github.com/MeteorsLiu/LRUCache"
+)
+
+func TestStore(t *testing.T) {
+	st := NewStore(cache.New(100), time.Hour, nil)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	s, err := st.Get(r, "sid")
+	if err != nil || !s.IsNew {
+		t.Fatalf("Get = %+v, %v, want a new session", s, err)
+	}
+	s.Values["user"] = "gopher"
+	w := httptest.NewRecorder()
+	if err := s.Save(r, w); err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != s.ID {
+		t.Fatalf("cookies = %v", cookies)
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(cookies[0])
+	s2, err := st.Get(r, "sid")
+	if err != nil || s2.IsNew || s2.Values["user"] != "gopher" {
+		t.Fatalf("Get = %+v, %v, want the saved session", s2, err)
+	}
+
+	s2.Options.MaxAge = -1
+	w = httptest.NewRecorder()
+	if err := s2.Save(r, w); err != nil {
+		t.Fatal(err)
+	}
+	if s3, _ := st.Get(r, "sid"); !s3.IsNew {
+		t.Fatal("a deleted session was loaded")
+	}
+}
+
+func TestSlidingExpiration(t *testing.T) {
+	st := NewStore(cache.New(100), 50*time.Millisecond, nil)
+	s, _ := st.New(httptest.NewRequest("GET", "/", nil), "sid")
+	w := httptest.NewRecorder()
+	s.Save(nil, w)
+	cookie := w.Result().Cookies()[0]
+
+	get := func() *Session {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.AddCookie(&http.Cookie{Name: "sid", Value: cookie.Value})
+		s, _ := st.Get(r, "sid")
+		return s
+	}
+	for i := 0; i < 4; i++ {
+		time.Sleep(20 * time.Millisecond)
+		if get().IsNew {
+			t.Fatal("an active session expired")
+		}
+	}
+	time.Sleep(80 * time.Millisecond)
+	if !get().IsNew {
+		t.Fatal("an idle session didn't expire")
+	}
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// Cacher is the interface of the basic cache operations, implemented
+// by Cache, Shadow and the clients of caches in other processes.
+type Cacher interface {
+	Get(key Key) (value interface{}, ok bool)
+	Set(key Key, value interface{})
+	SetWithExpire(key Key, value interface{}, ttl time.Duration)
+	Remove(key Key)
+}
+
+// Shadow is a Cacher serving from a primary cache while mirroring every
+// operation to a candidate one and comparing their lookups, to safely
+// evaluate a new policy or backend in production.
+type Shadow struct {
+	primary, candidate Cacher
+	// Equal compares the values of a key found in both caches.
+	// It defaults to reflect.DeepEqual.
+	Equal func(a, b interface{}) bool
+
+	lookups, hitOnly, missOnly, differ uint64
+}
+
+// ShadowStats count the lookups of a Shadow and how the candidate
+// diverged from the primary.
+type ShadowStats struct {
+	Lookups uint64
+	// PrimaryHitOnly and CandidateHitOnly count the keys found
+	// by only one of the caches.
+	PrimaryHitOnly   uint64
+	CandidateHitOnly uint64
+	// ValueDivergences counts the keys found by both with values
+	// which aren't Equal.
+	ValueDivergences uint64
+}
+
+// NewShadow returns a Shadow of primary mirrored to candidate.
+func NewShadow(primary, candidate Cacher) *Shadow {
+	return &Shadow{primary: primary, candidate: candidate}
+}
+
+// Get looks up key in both caches and returns the primary's value.
+func (s *Shadow) Get(key Key) (interface{}, bool) {
+	v, ok := s.primary.Get(key)
+	cv, cok := s.candidate.Get(key)
+	atomic.AddUint64(&s.lookups, 1)
+	switch {
+	case ok && !cok:
+		atomic.AddUint64(&s.hitOnly, 1)
+	case !ok && cok:
+		atomic.AddUint64(&s.missOnly, 1)
+	case ok && !s.equal(v, cv):
+		atomic.AddUint64(&s.differ, 1)
+	}
+	return v, ok
+}
+
+func (s *Shadow) equal(a, b interface{}) bool {
+	if s.Equal != nil {
+		return s.Equal(a, b)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// Set adds a value to both caches.
+func (s *Shadow) Set(key Key, value interface{}) {
+	s.primary.Set(key, value)
+	s.candidate.Set(key, value)
+}
+
+// SetWithExpire adds a value which expires after ttl to both caches.
+func (s *Shadow) SetWithExpire(key Key, value interface{}, ttl time.Duration) {
+	s.primary.SetWithExpire(key, value, ttl)
+	s.candidate.SetWithExpire(key, value, ttl)
+}
+
+// Remove removes key from both caches.
+func (s *Shadow) Remove(key Key) {
+	s.primary.Remove(key)
+	s.candidate.Remove(key)
+}
+
+// Stats returns the divergence statistics of the shadow.
+func (s *Shadow) Stats() ShadowStats {
+	return ShadowStats{
+		Lookups:          atomic.LoadUint64(&s.lookups),
+		PrimaryHitOnly:   atomic.LoadUint64(&s.hitOnly),
+		CandidateHitOnly: atomic.LoadUint64(&s.missOnly),
+		ValueDivergences: atomic.LoadUint64(&s.differ),
+	}
+}
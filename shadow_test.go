@@ -0,0 +1,29 @@
+package cache
+
+import "testing"
+
+var (
+	_ Cacher = (*Cache)(nil)
+	_ Cacher = (*Shadow)(nil)
+)
+
+func TestShadow(t *testing.T) {
+	primary, candidate := New(10), New(3)
+	s := NewShadow(primary, candidate)
+	for i := 0; i < 4; i++ {
+		s.Set(i, i)
+	}
+	for i := 0; i < 4; i++ {
+		if v, ok := s.Get(i); !ok || v != i {
+			t.Fatalf("Get(%d) = %v, %v, want the primary's value", i, v, ok)
+		}
+	}
+	candidate.Set(3, "other")
+	candidate.Set(9, 9)
+	s.Get(3)
+	s.Get(9)
+	want := ShadowStats{Lookups: 6, PrimaryHitOnly: 1, CandidateHitOnly: 1, ValueDivergences: 1}
+	if got := s.Stats(); got != want {
+		t.Fatalf("Stats() = %+v, want %+v", got, want)
+	}
+}
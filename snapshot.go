@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"container/list"
+	"sync/atomic"
+	"time"
+)
+
+// readSnapshot is an immutable copy of the index, read without c.mu.
+type readSnapshot struct {
+	values map[Key]snapValue
+}
+
+type snapValue struct {
+	value  interface{}
+	expire int64
+	// read points to the entry's read time under WithMaxIdle.
+	read *int64
+}
+
+// snapshots publishes the read snapshots, see WithReadSnapshots.
+type snapshots struct {
+	// quiet is how long writes must pause before a snapshot is built,
+	// and how often that's checked.
+	quiet int64
+	// cur holds the current *readSnapshot, nil after a write.
+	cur atomic.Value
+	// written is when the index last changed. It's guarded by c.mu.
+	written int64
+}
+
+// WithReadSnapshots is an experimental mode for workloads made almost
+// only of reads of a mostly static set of keys. Every quiet, if no
+// write happened for as long, a background task publishes an immutable
+// copy of the index, which Get then reads without taking the lock. Any
+// write drops the copy, Get takes the lock until the writes pause again
+// and the copy is rebuilt, within two quiet periods. The task runs
+// until Close is called.
+//
+// Lookups served from the copy don't move entries to the front nor
+// count their hits, so the eviction order only reflects the locked
+// lookups and WithMaxHits doesn't see them. WithMaxIdle does: the
+// lookups record their time, and the idle scan moves the entries read
+// since to the front before deciding. Building the copy costs
+// an allocation proportional to the number of entries, once per
+// pause at most. It panics if quiet isn't positive.
+func WithReadSnapshots(quiet time.Duration) Option {
+	if quiet <= 0 {
+		panic("cache: WithReadSnapshots quiet must be positive")
+	}
+	return func(c *Cache) {
+		c.snaps = &snapshots{quiet: int64(quiet)}
+		c.snaps.cur.Store((*readSnapshot)(nil))
+	}
+}
+
+// get looks up key in the current snapshot. done is false if there's
+// no snapshot or the entry expired, the lookup must take the lock then.
+func (s *snapshots) get(key Key, now int64) (value interface{}, ok, done bool) {
+	snap := s.cur.Load().(*readSnapshot)
+	if snap == nil {
+		return nil, false, false
+	}
+	v, hit := snap.values[key]
+	if !hit {
+		return nil, false, true
+	}
+	if v.expire > 0 && v.expire <= now {
+		return nil, false, false
+	}
+	if v.read != nil && atomic.LoadInt64(v.read) < now {
+		atomic.StoreInt64(v.read, now)
+	}
+	return v.value, true, true
+}
+
+// changed drops the snapshot after a write. The caller must hold c.mu.
+func (c *Cache) changed() {
+	if c.snaps == nil {
+		return
+	}
+	c.snaps.written = c.now()
+	if c.snaps.cur.Load().(*readSnapshot) != nil {
+		c.snaps.cur.Store((*readSnapshot)(nil))
+	}
+}
+
+// publishSnapshot builds a snapshot if there's none and the writes
+// paused for long enough.
+func (c *Cache) publishSnapshot() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.snaps
+	now := c.now()
+	if s.cur.Load().(*readSnapshot) != nil || now-s.written < s.quiet {
+		return
+	}
+	snap := &readSnapshot{values: make(map[Key]snapValue)}
+	if c.store != nil {
+		c.store.Range(func(key Key, ele *list.Element) bool {
+			e := ele.Value.(*entry)
+			v := snapValue{value: e.value, expire: e.expire}
+			if c.maxIdle > 0 {
+				v.read = &e.read
+			}
+			snap.values[key] = v
+			return true
+		})
+	}
+	s.cur.Store(snap)
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitSnapshot waits for c to publish a snapshot.
+func waitSnapshot(t *testing.T, c *Cache) {
+	t.Helper()
+	for i := 0; c.snaps.cur.Load().(*readSnapshot) == nil; i++ {
+		if i == 1000 {
+			t.Fatal("no snapshot published once the writes paused")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestReadSnapshots(t *testing.T) {
+	c := New(10, WithReadSnapshots(time.Millisecond))
+	defer c.Close()
+	c.Set("a", 1)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get = %v, %v", v, ok)
+	}
+	waitSnapshot(t, c)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get = %v, %v from the snapshot", v, ok)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("Get found a missing key in the snapshot")
+	}
+
+	c.Set("a", 2)
+	if c.snaps.cur.Load().(*readSnapshot) != nil {
+		t.Fatal("Set didn't drop the snapshot")
+	}
+	if v, ok := c.Get("a"); !ok || v != 2 {
+		t.Fatalf("Get = %v, %v after Set", v, ok)
+	}
+	if c.snaps.cur.Load().(*readSnapshot) != nil {
+		t.Fatal("a lookup rebuilt the snapshot")
+	}
+	c.Remove("a")
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("Get found a removed key")
+	}
+	if s := c.Stats(); s.Hits != 3 || s.Misses != 2 {
+		t.Fatalf("Stats = %d hits, %d misses, want 3, 2", s.Hits, s.Misses)
+	}
+}
+
+func TestReadSnapshotsQuiet(t *testing.T) {
+	c := New(10, WithReadSnapshots(time.Hour))
+	defer c.Close()
+	c.Set("a", 1)
+	c.Get("a")
+	if c.snaps.cur.Load().(*readSnapshot) != nil {
+		t.Fatal("snapshot published before the writes paused")
+	}
+}
+
+func TestReadSnapshotsExpired(t *testing.T) {
+	c := New(10, WithReadSnapshots(time.Millisecond))
+	defer c.Close()
+	c.SetWithExpire("a", 1, 20*time.Millisecond)
+	waitSnapshot(t, c)
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("Get returned an expired value from the snapshot")
+	}
+}
+
+func TestReadSnapshotsConcurrent(t *testing.T) {
+	c := New(100, WithReadSnapshots(time.Millisecond))
+	defer c.Close()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				k := strconv.Itoa(j % 50)
+				if j%10 == i {
+					c.Set(k, j)
+				}
+				c.Get(k)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestReadSnapshotsZeroQuiet(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("WithReadSnapshots(0) didn't panic")
+		}
+	}()
+	WithReadSnapshots(0)
+}
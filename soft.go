@@ -0,0 +1,92 @@
+package cache
+
+import "time"
+
+// softTTL records the TTLs of an entry added with SetWithExpire2.
+type softTTL struct {
+	// expire is when the entry becomes stale, zero if never.
+	expire int64
+	// ttl and hard are the TTLs the entry was added with.
+	ttl, hard time.Duration
+}
+
+// SetWithExpire2 adds a value to the cache with two expirations.
+// After softTTL the entry is still served but flagged as stale, so
+// GetOrLoad refreshes it; after hardTTL it isn't served at all.
+// A softTTL which isn't shorter than hardTTL never flags the entry.
+// hardTTL is clamped like in SetWithExpire.
+func (c *Cache) SetWithExpire2(key Key, value interface{}, softTTL, hardTTL time.Duration) {
+	if !c.allowWrite() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expire := c.expireAt(hardTTL)
+	e := c.insert(key, value, expire)
+	if e == nil {
+		return
+	}
+	e.soft.ttl, e.soft.hard = softTTL, hardTTL
+	if soft := c.now() + int64(softTTL); softTTL > 0 && (e.expire == 0 || soft < e.expire) {
+		e.soft.expire = soft
+	}
+	c.evict()
+}
+
+// Result is the outcome of GetResult and GetOrLoadResult.
+type Result struct {
+	Value interface{}
+	// Ok reports whether the value was found.
+	Ok bool
+	// Stale reports whether the value is past its soft TTL or, from
+	// GetOrLoadResult, whether it expired and is served because
+	// loading it failed.
+	Stale bool
+
+	// SoftExpire is when the value becomes stale and
+	// Expire when it expires. They are zero if it never does.
+	SoftExpire time.Time
+	Expire     time.Time
+
+	// SoftTTL and TTL are the TTLs passed to SetWithExpire2.
+	SoftTTL time.Duration
+	TTL     time.Duration
+}
+
+// GetResult looks up a key's value like Get, also reporting whether
+// it's stale and its expirations. It's on the GetOrLoad hot path,
+// so it unlocks explicitly rather than deferring it.
+func (c *Cache) GetResult(key Key) (r Result) {
+	c.mu.Lock()
+	if c.store == nil {
+		c.mu.Unlock()
+		return
+	}
+	ele, hit := c.store.Get(key)
+	if !hit {
+		c.miss(key)
+		c.mu.Unlock()
+		return
+	}
+	e := ele.Value.(*entry)
+	now := c.now()
+	if e.expired(now) {
+		c.expire(ele)
+		c.miss(key)
+		c.mu.Unlock()
+		return
+	}
+	c.touch(ele)
+	c.hit(e, now)
+	r.Value, r.Ok = e.value, true
+	if e.expire > 0 {
+		r.Expire = wallTime(e.expire)
+	}
+	if e.soft.expire > 0 {
+		r.SoftExpire = wallTime(e.soft.expire)
+		r.Stale = now >= e.soft.expire
+	}
+	r.SoftTTL, r.TTL = e.soft.ttl, e.soft.hard
+	c.mu.Unlock()
+	return
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSoftTTL(t *testing.T) {
+	ce := New(10)
+	ce.SetWithExpire2("a", 1, 10*time.Millisecond, time.Hour)
+	if r := ce.GetResult("a"); !r.Ok || r.Stale || r.Value != 1 {
+		t.Fatalf("fresh GetResult = %+v", r)
+	}
+	time.Sleep(20 * time.Millisecond)
+	r := ce.GetResult("a")
+	if !r.Ok || !r.Stale || r.SoftExpire.IsZero() || r.Expire.IsZero() {
+		t.Fatalf("stale GetResult = %+v", r)
+	}
+
+	refreshed := make(chan struct{})
+	v, err := ce.GetOrLoad("a", func(Key) (interface{}, error) {
+		defer close(refreshed)
+		return 2, nil
+	})
+	if err != nil || v != 1 {
+		t.Fatalf("GetOrLoad = %v, %v, want the stale value", v, err)
+	}
+	<-refreshed
+	time.Sleep(10 * time.Millisecond)
+	if r := ce.GetResult("a"); r.Value != 2 || r.SoftTTL != 10*time.Millisecond {
+		t.Fatalf("refreshed GetResult = %+v", r)
+	}
+
+	ce.Set("a", 3)
+	if r := ce.GetResult("a"); !r.SoftExpire.IsZero() || !r.Expire.IsZero() {
+		t.Fatalf("Set kept the soft TTL: %+v", r)
+	}
+}
+
+func TestSoftTTLSingleRefresh(t *testing.T) {
+	ce := New(10, WithWorkers(1))
+	defer ce.Close()
+	ce.SetWithExpire2("a", 1, time.Millisecond, time.Hour)
+	time.Sleep(5 * time.Millisecond)
+	release := make(chan struct{})
+	defer close(release)
+	loader := func(Key) (interface{}, error) {
+		<-release
+		return 2, nil
+	}
+	for i := 0; i < 100; i++ {
+		if v, err := ce.GetOrLoad("a", loader); v != 1 || err != nil {
+			t.Fatalf("GetOrLoad = %v, %v, want the stale value", v, err)
+		}
+	}
+	// Only the first stale hit queued a refresh.
+	if q := ce.Stats().Queued; q > 1 {
+		t.Fatalf("%d refreshes queued behind the one in flight", q)
+	}
+}
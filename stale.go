@@ -0,0 +1,120 @@
+package cache
+
+import "time"
+
+// staleValues keeps the values of expired entries for a while, to be
+// served if reloading them fails, see WithServeStaleOnError.
+// It's guarded by Cache.mu.
+type staleValues struct {
+	// maxStale is how long past its expiration a value is kept.
+	maxStale int64
+	values   map[Key]staleValue
+	// order lists the kept values by the time they were kept, which is
+	// about the order they're dropped in, so pruning only looks at the
+	// values due. Values dropped or kept again are skipped.
+	order []staleKey
+}
+
+type staleKey struct {
+	key   Key
+	until int64
+}
+
+type staleValue struct {
+	value interface{}
+	// until is when the value is dropped.
+	until int64
+}
+
+// WithServeStaleOnError makes GetOrLoad return the value of an entry
+// which expired less than maxStale ago when loading it again fails,
+// rather than the error, improving availability during backend
+// outages. GetOrLoadResult flags such values as stale. Setting or
+// removing the key drops its stale value. At most MaxEntries stale
+// values are kept, if it's set, the oldest are dropped first. Values
+// spent by WithMaxHits aren't kept.
+func WithServeStaleOnError(maxStale time.Duration) Option {
+	return func(c *Cache) {
+		c.stale.maxStale = int64(maxStale)
+	}
+}
+
+// keep stashes the value of the expired entry e, dropping the values
+// past their time and the oldest ones beyond limit, unless it's zero.
+// The caller must hold c.mu.
+func (s *staleValues) keep(e *entry, now int64, limit int) {
+	if s.maxStale <= 0 {
+		return
+	}
+	if s.values == nil {
+		s.values = make(map[Key]staleValue)
+	}
+	until := e.expire + s.maxStale
+	s.values[e.key] = staleValue{value: e.value, until: until}
+	s.order = append(s.order, staleKey{e.key, until})
+	s.prune(now)
+	for limit > 0 && len(s.values) > limit {
+		s.drop()
+	}
+	if len(s.order) > 2*len(s.values)+16 {
+		s.compact()
+	}
+}
+
+// compact removes the skipped keys from the order.
+// The caller must hold c.mu.
+func (s *staleValues) compact() {
+	order := make([]staleKey, 0, len(s.values))
+	for _, k := range s.order {
+		if v, ok := s.values[k.key]; ok && v.until == k.until {
+			order = append(order, k)
+		}
+	}
+	s.order = order
+}
+
+// forget drops the stale value of key. The caller must hold c.mu.
+func (s *staleValues) forget(key Key) {
+	if s.values != nil {
+		delete(s.values, key)
+	}
+}
+
+// reset drops every value. The caller must hold c.mu.
+func (s *staleValues) reset() {
+	s.values = nil
+	s.order = nil
+}
+
+// prune drops the values kept past their time.
+// The caller must hold c.mu.
+func (s *staleValues) prune(now int64) {
+	for len(s.order) > 0 && now >= s.order[0].until {
+		s.drop()
+	}
+	if len(s.values) == 0 {
+		s.order = nil
+	}
+}
+
+// drop drops the oldest value kept. The caller must hold c.mu.
+func (s *staleValues) drop() {
+	k := s.order[0]
+	s.order[0] = staleKey{}
+	s.order = s.order[1:]
+	// The key may have been dropped or kept again since.
+	if v, ok := s.values[k.key]; ok && v.until == k.until {
+		delete(s.values, k.key)
+	}
+}
+
+// staleValue returns the stale value of key, if any.
+func (c *Cache) staleValue(key Key) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.stale.values[key]
+	if !ok || c.now() >= v.until {
+		return nil, false
+	}
+	return v.value, true
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestServeStaleOnError(t *testing.T) {
+	c := New(10, WithServeStaleOnError(50*time.Millisecond))
+	errDown := errors.New("backend down")
+	down := func(Key) (interface{}, error) { return nil, errDown }
+
+	c.SetWithExpire("a", 1, time.Millisecond)
+	c.SetWithExpire("b", 2, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("Get returned an expired value")
+	}
+	r, err := c.GetOrLoadResult("a", down)
+	if err != nil || r.Value != 1 || !r.Stale {
+		t.Fatalf("GetOrLoadResult = %+v, %v", r, err)
+	}
+	if v, err := c.GetOrLoad("a", down); err != nil || v != 1 {
+		t.Fatalf("GetOrLoad = %v, %v", v, err)
+	}
+
+	c.Remove("b")
+	if _, err := c.GetOrLoad("b", down); err != errDown {
+		t.Fatalf("GetOrLoad of a removed key error = %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, err := c.GetOrLoad("a", down); err != errDown {
+		t.Fatalf("GetOrLoad past the max stale window error = %v", err)
+	}
+	c.Len()
+	if len(c.stale.values) != 0 {
+		t.Fatalf("%d stale values weren't pruned", len(c.stale.values))
+	}
+}
+
+func TestServeStaleBounded(t *testing.T) {
+	c := New(10, WithServeStaleOnError(time.Hour))
+	for i := 0; i < 100; i++ {
+		c.SetWithExpire(i, i, time.Nanosecond)
+		time.Sleep(time.Microsecond)
+		// Expired on lookup, without a janitor.
+		c.Get(i)
+	}
+	if n := len(c.stale.values); n != 10 {
+		t.Fatalf("%d stale values kept, want MaxEntries", n)
+	}
+	if n := len(c.stale.order); n > 2*10+16 {
+		t.Fatalf("stale order holds %d keys", n)
+	}
+	if v, ok := c.staleValue(99); !ok || v != 99 {
+		t.Fatal("the newest stale value was dropped")
+	}
+
+	// Without MaxEntries, values past their time are dropped on keep.
+	c = New(0, WithServeStaleOnError(time.Millisecond))
+	for i := 0; i < 100; i++ {
+		c.SetWithExpire(i, i, time.Nanosecond)
+		time.Sleep(100 * time.Microsecond)
+		c.Get(i)
+	}
+	if n := len(c.stale.values); n > 50 {
+		t.Fatalf("%d stale values kept past their time", n)
+	}
+}
+
+func TestServeStaleMaxHits(t *testing.T) {
+	c := New(10, WithServeStaleOnError(time.Minute), WithMaxHits(1))
+	errDown := errors.New("backend down")
+	c.Set("token", "secret")
+	if v, ok := c.Get("token"); !ok || v != "secret" {
+		t.Fatalf("Get = %v, %v", v, ok)
+	}
+	if v, err := c.GetOrLoad("token", func(Key) (interface{}, error) { return nil, errDown }); err != errDown {
+		t.Fatalf("GetOrLoad served the spent value %v, %v", v, err)
+	}
+}
@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Stats are the statistics of a Cache.
+type Stats struct {
+	// Hits and Misses count the lookups through Get and its variants.
+	Hits   uint64
+	Misses uint64
+	// Evictions counts the entries removed to stay within MaxEntries
+	// or MaxCost, or because they were idle, see WithMaxIdle.
+	// Expirations counts those removed because they expired.
+	Evictions   uint64
+	Expirations uint64
+	// Rejected counts the writes rejected by the write limiter.
+	Rejected uint64
+	// TypeMismatches counts the writes caught by the type guard,
+	// see WithTypeGuard.
+	TypeMismatches uint64
+	// InFlight is the number of GetOrLoad loads in flight, see InFlight.
+	InFlight int
+	// Queued is the number of tasks waiting for a worker, see WithWorkers,
+	// and of eviction callbacks waiting for the dispatcher, see
+	// WithOrderedEvictions.
+	Queued int
+	// Dropped counts the tasks the worker pool dropped because its
+	// queue was full or the cache was closed, see WithWorkers.
+	Dropped uint64
+	// DistinctKeys is the estimated number of distinct keys seen,
+	// see WithKeyCardinality.
+	DistinctKeys uint64
+	// Prefixes are the lookups by key prefix, see WithPrefixStats.
+	Prefixes map[string]PrefixCounts
+	// Windows are the lookup rates over RollingWindows,
+	// see WithRollingStats.
+	Windows []WindowStats
+	// Steady reports whether the cache reached a steady state,
+	// see WithSteadyState.
+	Steady bool
+
+	// Lifetime is the distribution of the time entries spent in the
+	// cache, from when they were set to when they were removed.
+	Lifetime Histogram
+	// Idle is the distribution of the time removed entries had gone
+	// unused. Together with Lifetime it shows whether TTLs or the
+	// capacity are limiting the cache.
+	Idle Histogram
+}
+
+// HistogramBounds are the upper bounds of the Histogram buckets.
+var HistogramBounds = [...]time.Duration{
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+	time.Minute,
+	10 * time.Minute,
+	time.Hour,
+	24 * time.Hour,
+}
+
+// Histogram is a distribution of durations. Counts[i] is the number of
+// observations up to HistogramBounds[i], the last count is for those
+// over every bound.
+type Histogram struct {
+	Counts [len(HistogramBounds) + 1]uint64
+	Count  uint64
+	Sum    time.Duration
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	i := 0
+	for i < len(HistogramBounds) && d > HistogramBounds[i] {
+		i++
+	}
+	h.Counts[i]++
+	h.Count++
+	h.Sum += d
+}
+
+// stats is guarded by Cache.mu.
+type stats struct {
+	evictions, expirations uint64
+	typeMismatches         uint64
+	lifetime, idle         Histogram
+}
+
+// removed records the removal of e at now.
+func (s *stats) removed(e *entry, now int64) {
+	s.lifetime.observe(time.Duration(now - e.created))
+	s.idle.observe(time.Duration(now - e.accessed))
+}
+
+// hit records a lookup which returned e. The caller must hold c.mu.
+func (c *Cache) hit(e *entry, now int64) {
+	e.hits++
+	e.accessed = now
+	c.hits.add(1)
+	c.prefixes.count(e.key, true)
+	if c.maxHits > 0 && e.hits >= c.maxHits && (e.expire == 0 || e.expire > now) {
+		// Spent, expire it on the next lookup.
+		c.timers.remove(e)
+		e.expire = now
+		c.timers.add(e)
+		c.changed()
+	}
+}
+
+// Stats returns the statistics of the cache.
+func (c *Cache) Stats() Stats {
+	c.loads.mu.Lock()
+	inFlight := len(c.loads.calls)
+	c.loads.mu.Unlock()
+	hits, misses := c.hits.load(), c.misses.load()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{
+		Hits:           hits,
+		Misses:         misses,
+		Evictions:      c.stats.evictions,
+		Expirations:    c.stats.expirations,
+		Rejected:       c.rejected.load(),
+		InFlight:       inFlight,
+		Queued:         c.pool.queued() + c.dispatcher.queued(),
+		Dropped:        c.pool.droppedTasks(),
+		DistinctKeys:   c.cardinality.estimate(),
+		Prefixes:       c.prefixes.snapshot(c.keyString),
+		Windows:        c.rolling.windows(c.now(), hits, misses),
+		Steady:         c.steady.reached(),
+		TypeMismatches: c.stats.typeMismatches,
+		Lifetime:       c.stats.lifetime,
+		Idle:           c.stats.idle,
+	}
+}
+
+// WritePrometheus writes the statistics of the cache to w in the
+// Prometheus text exposition format, with every metric name
+// prefixed by name.
+func (c *Cache) WritePrometheus(w io.Writer, name string) error {
+	s := c.Stats()
+	ew := &errWriter{w: w}
+	for _, m := range []struct {
+		name string
+		v    uint64
+	}{
+		{"hits_total", s.Hits},
+		{"misses_total", s.Misses},
+		{"evictions_total", s.Evictions},
+		{"expirations_total", s.Expirations},
+		{"rejected_writes_total", s.Rejected},
+		{"type_mismatches_total", s.TypeMismatches},
+		{"dropped_tasks_total", s.Dropped},
+	} {
+		ew.printf("# TYPE %s_%s counter\n%s_%s %d\n", name, m.name, name, m.name, m.v)
+	}
+	ew.printf("# TYPE %s_entries gauge\n%s_entries %d\n", name, name, c.ApproxLen())
+	ew.printf("# TYPE %s_loads_in_flight gauge\n%s_loads_in_flight %d\n", name, name, s.InFlight)
+	ew.printf("# TYPE %s_queued_tasks gauge\n%s_queued_tasks %d\n", name, name, s.Queued)
+	ew.printf("# TYPE %s_distinct_keys gauge\n%s_distinct_keys %d\n", name, name, s.DistinctKeys)
+	writeHistogram(ew, name+"_lifetime_seconds", &s.Lifetime)
+	writeHistogram(ew, name+"_idle_seconds", &s.Idle)
+	return ew.err
+}
+
+func writeHistogram(ew *errWriter, name string, h *Histogram) {
+	ew.printf("# TYPE %s histogram\n", name)
+	var cum uint64
+	for i, b := range HistogramBounds {
+		cum += h.Counts[i]
+		ew.printf("%s_bucket{le=\"%g\"} %d\n", name, b.Seconds(), cum)
+	}
+	ew.printf("%s_bucket{le=\"+Inf\"} %d\n", name, h.Count)
+	ew.printf("%s_sum %g\n%s_count %d\n", name, h.Sum.Seconds(), name, h.Count)
+}
+
+// errWriter keeps the first error of a sequence of writes.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, args...)
+	}
+}
@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	ce := New(2)
+	ce.Set("a", 1)
+	ce.Get("a")
+	ce.Get("missing")
+	ce.Set("b", 2)
+	ce.Set("c", 3)
+	ce.SetWithExpire("d", 4, time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	ce.Get("d")
+
+	s := ce.Stats()
+	if s.Hits != 1 || s.Misses != 2 || s.Evictions != 2 || s.Expirations != 1 {
+		t.Fatalf("Stats = %+v", s)
+	}
+	if s.Lifetime.Count != 3 || s.Idle.Count != 3 {
+		t.Fatalf("histograms observed %d, %d removals, want 3", s.Lifetime.Count, s.Idle.Count)
+	}
+	if s.Lifetime.Counts[len(HistogramBounds)] != 0 {
+		t.Fatal("short lifetimes landed in the overflow bucket")
+	}
+
+	var sb strings.Builder
+	if err := ce.WritePrometheus(&sb, "lru"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"lru_hits_total 1\n",
+		"lru_lifetime_seconds_bucket{le=\"+Inf\"} 3\n",
+		"lru_idle_seconds_count 3\n",
+	} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("exposition lacks %q:\n%s", want, sb.String())
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// steadyWindows is the number of consecutive windows whose hit
+	// ratios must agree within steadyTolerance for a steady state.
+	steadyWindows   = 3
+	steadyTolerance = 0.01
+)
+
+// steadyState detects when a cache has warmed up.
+type steadyState struct {
+	window time.Duration
+	mu     sync.Mutex
+	last   Stats
+	ratios []float64
+	steady bool
+}
+
+// WithSteadyState samples the hit ratio of the
+// cache every window, reporting a steady state in Stats once evictions
+// have started and the hit ratios of the last 3 windows agree within
+// 1%, so benchmarks and autoscalers know when their measurements are
+// meaningful. The sampling runs until Close is called.
+// It panics if window isn't positive.
+func WithSteadyState(window time.Duration) Option {
+	if window <= 0 {
+		panic("cache: WithSteadyState window must be positive")
+	}
+	return func(c *Cache) {
+		c.steady = &steadyState{window: window}
+	}
+}
+
+func (c *Cache) sampleSteady() {
+	c.steady.sample(c.Stats())
+}
+
+// sample records the hit ratio of the window ending with s.
+func (st *steadyState) sample(s Stats) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	hits, misses := s.Hits-st.last.Hits, s.Misses-st.last.Misses
+	st.last = s
+	if hits+misses == 0 {
+		// Idle windows don't tell anything.
+		return
+	}
+	st.ratios = append(st.ratios, float64(hits)/float64(hits+misses))
+	if len(st.ratios) > steadyWindows {
+		st.ratios = st.ratios[1:]
+	}
+	if s.Evictions == 0 || len(st.ratios) < steadyWindows {
+		st.steady = false
+		return
+	}
+	lo, hi := st.ratios[0], st.ratios[0]
+	for _, r := range st.ratios[1:] {
+		lo, hi = math.Min(lo, r), math.Max(hi, r)
+	}
+	st.steady = hi-lo <= steadyTolerance
+}
+
+func (st *steadyState) reached() bool {
+	if st == nil {
+		return false
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.steady
+}
@@ -0,0 +1,34 @@
+package cache
+
+import "testing"
+
+func TestSteadyState(t *testing.T) {
+	var st steadyState
+	var s Stats
+	for i, want := range []bool{false, false, false, true, true, false} {
+		s.Hits += 90
+		s.Misses += 10
+		if i >= 3 {
+			s.Evictions += 10
+		}
+		if i == 5 {
+			s.Hits -= 50
+		}
+		st.sample(s)
+		if st.reached() != want {
+			t.Fatalf("window %d: steady = %v, want %v", i, !want, want)
+		}
+	}
+	if (*steadyState)(nil).reached() {
+		t.Fatal("steady without WithSteadyState")
+	}
+}
+
+func TestSteadyStateZeroWindow(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("WithSteadyState with a zero window didn't panic")
+		}
+	}()
+	New(10, WithSteadyState(0))
+}
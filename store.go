@@ -0,0 +1,66 @@
+package cache
+
+import "container/list"
+
+// A Store indexes the entries of a Cache by key. The Cache owns the
+// recency list and the eviction policy; the Store only maps keys to
+// list elements, so alternative engines can be swapped in and
+// benchmarked independently.
+//
+// A Store doesn't need to be safe for concurrent access, the Cache
+// serializes every call.
+type Store interface {
+	// Get returns the element stored for key.
+	Get(key Key) (*list.Element, bool)
+	// Set stores the element for key, replacing any previous one.
+	Set(key Key, ele *list.Element)
+	// Delete removes key from the store.
+	Delete(key Key)
+	// Len returns the number of stored keys.
+	Len() int
+	// Range calls fn for every stored key until fn returns false.
+	// fn must not modify the store.
+	Range(fn func(key Key, ele *list.Element) bool)
+}
+
+// WithStore makes the cache index its entries in the stores
+// created by newStore instead of the default map store.
+// newStore is called again whenever the cache is rebuilt, e.g. after Clear.
+func WithStore(newStore func() Store) Option {
+	return func(c *Cache) {
+		c.newStore = newStore
+	}
+}
+
+// mapStore is the default Store backed by a Go map.
+type mapStore map[interface{}]*list.Element
+
+// NewMapStore returns a Store backed by a Go map.
+func NewMapStore() Store {
+	return make(mapStore)
+}
+
+func (m mapStore) Get(key Key) (*list.Element, bool) {
+	ele, ok := m[key]
+	return ele, ok
+}
+
+func (m mapStore) Set(key Key, ele *list.Element) {
+	m[key] = ele
+}
+
+func (m mapStore) Delete(key Key) {
+	delete(m, key)
+}
+
+func (m mapStore) Len() int {
+	return len(m)
+}
+
+func (m mapStore) Range(fn func(key Key, ele *list.Element) bool) {
+	for k, e := range m {
+		if !fn(k, e) {
+			return
+		}
+	}
+}
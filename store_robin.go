@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"container/list"
+	"hash/maphash"
+)
+
+// robinStore is an open-addressing Store using robin-hood hashing.
+// Key hashes are kept inline in the slot array, so a lookup mostly
+// touches one contiguous region of memory, and there are no per-entry
+// bucket allocations. Keys of types it can't hash (anything other than
+// strings and integers) are kept in a regular map.
+type robinStore struct {
+	seed    maphash.Seed
+	intSeed uint64
+	slots   []robinSlot
+	// n is the number of used slots.
+	n     int
+	other map[interface{}]*list.Element
+}
+
+type robinSlot struct {
+	// hash is the key's hash, zero marks an empty slot.
+	hash uint64
+	key  Key
+	ele  *list.Element
+}
+
+const robinMinSize = 8
+
+// NewRobinStore returns a Store backed by an open-addressing
+// robin-hood hash table. It trades the flexibility of a Go map
+// for lower per-entry overhead and better lookup locality, which
+// matters for caches holding tens of millions of entries.
+func NewRobinStore() Store {
+	s := &robinStore{
+		seed:  maphash.MakeSeed(),
+		slots: make([]robinSlot, robinMinSize),
+		other: make(map[interface{}]*list.Element),
+	}
+	var h maphash.Hash
+	h.SetSeed(s.seed)
+	h.WriteString("int")
+	s.intSeed = h.Sum64()
+	return s
+}
+
+// hash returns the hash of key, or false if key has to be kept in the
+// fallback map. The returned hash is never zero.
+func (s *robinStore) hash(key Key) (uint64, bool) {
+	var x uint64
+	switch k := key.(type) {
+	case string:
+		var h maphash.Hash
+		h.SetSeed(s.seed)
+		h.WriteString(k)
+		x = h.Sum64()
+	case int:
+		x = mix64(uint64(k) ^ s.intSeed)
+	case int64:
+		x = mix64(uint64(k) ^ s.intSeed)
+	case int32:
+		x = mix64(uint64(k) ^ s.intSeed)
+	case uint:
+		x = mix64(uint64(k) ^ s.intSeed)
+	case uint64:
+		x = mix64(k ^ s.intSeed)
+	case uint32:
+		x = mix64(uint64(k) ^ s.intSeed)
+	default:
+		return 0, false
+	}
+	if x == 0 {
+		x = 1
+	}
+	return x, true
+}
+
+// mix64 is the splitmix64 finalizer.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+func (s *robinStore) mask() uint64 {
+	return uint64(len(s.slots) - 1)
+}
+
+// dist returns how far the slot i is from the home slot of hash.
+func (s *robinStore) dist(hash uint64, i uint64) uint64 {
+	return (i - hash&s.mask()) & s.mask()
+}
+
+func (s *robinStore) Get(key Key) (*list.Element, bool) {
+	hash, ok := s.hash(key)
+	if !ok {
+		ele, ok := s.other[key]
+		return ele, ok
+	}
+	mask := s.mask()
+	for i, d := hash&mask, uint64(0); ; i, d = (i+1)&mask, d+1 {
+		slot := &s.slots[i]
+		// A robin-hood table keeps every key at most as far from home
+		// as the keys it passed, so a poorer slot ends the probe.
+		if slot.hash == 0 || s.dist(slot.hash, i) < d {
+			return nil, false
+		}
+		if slot.hash == hash && slot.key == key {
+			return slot.ele, true
+		}
+	}
+}
+
+func (s *robinStore) Set(key Key, ele *list.Element) {
+	hash, ok := s.hash(key)
+	if !ok {
+		s.other[key] = ele
+		return
+	}
+	if (s.n+1)*8 > len(s.slots)*7 {
+		s.grow()
+	}
+	s.insert(robinSlot{hash: hash, key: key, ele: ele})
+}
+
+func (s *robinStore) insert(cur robinSlot) {
+	mask := s.mask()
+	for i, d := cur.hash&mask, uint64(0); ; i, d = (i+1)&mask, d+1 {
+		slot := &s.slots[i]
+		if slot.hash == 0 {
+			*slot = cur
+			s.n++
+			return
+		}
+		if slot.hash == cur.hash && slot.key == cur.key {
+			slot.ele = cur.ele
+			return
+		}
+		// Take from the rich: the displaced slot continues probing.
+		if sd := s.dist(slot.hash, i); sd < d {
+			cur, *slot = *slot, cur
+			d = sd
+		}
+	}
+}
+
+func (s *robinStore) grow() {
+	old := s.slots
+	s.slots = make([]robinSlot, len(old)*2)
+	s.n = 0
+	for _, slot := range old {
+		if slot.hash != 0 {
+			s.insert(slot)
+		}
+	}
+}
+
+func (s *robinStore) Delete(key Key) {
+	hash, ok := s.hash(key)
+	if !ok {
+		delete(s.other, key)
+		return
+	}
+	mask := s.mask()
+	i := hash & mask
+	for d := uint64(0); ; i, d = (i+1)&mask, d+1 {
+		slot := &s.slots[i]
+		if slot.hash == 0 || s.dist(slot.hash, i) < d {
+			return
+		}
+		if slot.hash == hash && slot.key == key {
+			break
+		}
+	}
+	// Backward shift deletion keeps the table tombstone free.
+	for {
+		next := (i + 1) & mask
+		if s.slots[next].hash == 0 || s.dist(s.slots[next].hash, next) == 0 {
+			s.slots[i] = robinSlot{}
+			break
+		}
+		s.slots[i] = s.slots[next]
+		i = next
+	}
+	s.n--
+}
+
+func (s *robinStore) Len() int {
+	return s.n + len(s.other)
+}
+
+func (s *robinStore) Range(fn func(key Key, ele *list.Element) bool) {
+	for _, slot := range s.slots {
+		if slot.hash != 0 && !fn(slot.key, slot.ele) {
+			return
+		}
+	}
+	for k, e := range s.other {
+		if !fn(k, e) {
+			return
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"container/list"
+	"math/rand"
+	"testing"
+)
+
+func TestRobinStore(t *testing.T) {
+	s := NewRobinStore()
+	want := make(map[interface{}]*list.Element)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100000; i++ {
+		var key Key
+		switch r.Intn(3) {
+		case 0:
+			key = r.Intn(5000)
+		case 1:
+			key = string(rune('a' + r.Intn(26)))
+		default:
+			key = struct{ a, b int }{r.Intn(50), r.Intn(50)}
+		}
+		if r.Intn(3) == 0 {
+			s.Delete(key)
+			delete(want, key)
+			continue
+		}
+		ele := &list.Element{Value: i}
+		s.Set(key, ele)
+		want[key] = ele
+	}
+	if s.Len() != len(want) {
+		t.Fatalf("Len() = %d, want %d", s.Len(), len(want))
+	}
+	for k, e := range want {
+		if got, ok := s.Get(k); !ok || got != e {
+			t.Fatalf("Get(%v) = %v, %v", k, got, ok)
+		}
+	}
+	n := 0
+	s.Range(func(k Key, e *list.Element) bool {
+		if want[k] != e {
+			t.Fatalf("Range yielded stale %v", k)
+		}
+		n++
+		return true
+	})
+	if n != len(want) {
+		t.Fatalf("Range visited %d keys, want %d", n, len(want))
+	}
+	if _, ok := s.Get(-1); ok {
+		t.Fatal("Get of a missing key hit")
+	}
+}
+
+func BenchmarkRobinStore(b *testing.B) {
+	benchmarkStore(b, NewRobinStore)
+}
+
+func BenchmarkMapStore(b *testing.B) {
+	benchmarkStore(b, NewMapStore)
+}
+
+func benchmarkStore(b *testing.B, newStore func() Store) {
+	ce := New(1<<16, WithStore(newStore))
+	for i := 0; i < b.N; i++ {
+		ce.Set(i&(1<<17-1), i)
+		ce.Get(i & (1<<16 - 1))
+	}
+}
@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"container/list"
+	"testing"
+)
+
+type countingStore struct {
+	Store
+	sets int
+}
+
+func (s *countingStore) Set(key Key, ele *list.Element) {
+	s.sets++
+	s.Store.Set(key, ele)
+}
+
+func TestWithStore(t *testing.T) {
+	var stores []*countingStore
+	ce := New(2, WithStore(func() Store {
+		s := &countingStore{Store: NewMapStore()}
+		stores = append(stores, s)
+		return s
+	}))
+	ce.Set("a", 1)
+	ce.Set("b", 2)
+	if v, ok := ce.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v", v, ok)
+	}
+	if stores[0].sets != 2 {
+		t.Fatalf("store saw %d sets, want 2", stores[0].sets)
+	}
+
+	ce.Clear()
+	ce.Set("c", 3)
+	if len(stores) != 2 {
+		t.Fatalf("Clear should rebuild the store, got %d stores", len(stores))
+	}
+	if !ce.Has("c") || ce.Has("a") {
+		t.Fatal("rebuilt store has wrong contents")
+	}
+}
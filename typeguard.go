@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// TypeMismatchError reports a write of a value whose type differs
+// from the one of the value cached for its key.
+type TypeMismatchError struct {
+	Key       Key
+	Want, Got reflect.Type
+	// name is the key as rendered by the cache, see WithKeyRedactor.
+	name string
+}
+
+func (e *TypeMismatchError) Error() string {
+	name := e.name
+	if name == "" {
+		name = fmt.Sprint(e.Key)
+	}
+	return fmt.Sprintf("cache: key %s holds a %v, got a %v", name, e.Want, e.Got)
+}
+
+// typeGuard is set by WithTypeGuard.
+type typeGuard struct {
+	reject     bool
+	onMismatch func(err *TypeMismatchError)
+	// departed holds the types of the keys which left the cache.
+	departed *Cache
+}
+
+// guardedKeys is how many keys which left the cache WithTypeGuard
+// remembers the type of.
+const guardedKeys = 4096
+
+// WithTypeGuard checks that the values written for a key keep the type
+// of the cached one, catching modules which share a key namespace by
+// mistake. The type of a key which left the cache, evicted, expired or
+// removed, is remembered too, for the 4096 keys which left it last, so
+// a later write is checked against it. Clear and Reset forget them.
+// Every mismatch is counted in Stats and, if onMismatch isn't
+// nil, passed to it, e.g. to log it. onMismatch is called with the
+// cache locked and must not use it. If reject is set the write is
+// dropped, otherwise it proceeds.
+func WithTypeGuard(reject bool, onMismatch func(err *TypeMismatchError)) Option {
+	return func(c *Cache) {
+		c.typeGuard = &typeGuard{reject: reject, onMismatch: onMismatch, departed: New(guardedKeys)}
+	}
+}
+
+// allowType reports whether value may replace the value of e.
+// The caller must hold c.mu.
+func (c *Cache) allowType(e *entry, value interface{}) bool {
+	if c.typeGuard == nil {
+		return true
+	}
+	return c.checkType(e.key, reflect.TypeOf(e.value), value)
+}
+
+// allowNewType reports whether value may be added for key, which isn't
+// in the cache. The caller must hold c.mu.
+func (c *Cache) allowNewType(key Key, value interface{}) bool {
+	if c.typeGuard == nil {
+		return true
+	}
+	want, ok := c.typeGuard.departed.Peek(key)
+	if !ok {
+		return true
+	}
+	if !c.checkType(key, want.(reflect.Type), value) {
+		return false
+	}
+	// The entry holds the type from now on.
+	c.typeGuard.departed.Remove(key)
+	return true
+}
+
+// departed remembers the type of e, which left the cache.
+// The caller must hold c.mu.
+func (c *Cache) departed(e *entry) {
+	if c.typeGuard != nil {
+		c.typeGuard.departed.Set(e.key, reflect.TypeOf(e.value))
+	}
+}
+
+// checkType reports whether value may be written for key, which holds
+// a want. The caller must hold c.mu.
+func (c *Cache) checkType(key Key, want reflect.Type, value interface{}) bool {
+	got := reflect.TypeOf(value)
+	if want == got {
+		return true
+	}
+	c.stats.typeMismatches++
+	if c.typeGuard.onMismatch != nil {
+		c.typeGuard.onMismatch(&TypeMismatchError{Key: key, Want: want, Got: got, name: c.keyString(key)})
+	}
+	return !c.typeGuard.reject
+}
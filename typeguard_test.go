@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTypeGuard(t *testing.T) {
+	var mismatches []*TypeMismatchError
+	report := func(err *TypeMismatchError) { mismatches = append(mismatches, err) }
+
+	c := New(10, WithTypeGuard(false, report))
+	c.Set("user:1", "alice")
+	c.Set("user:1", 42)
+	if v, _ := c.Get("user:1"); v != 42 {
+		t.Fatalf("Get = %v, the write should have proceeded", v)
+	}
+	if len(mismatches) != 1 || mismatches[0].Want != reflect.TypeOf("") || mismatches[0].Got != reflect.TypeOf(0) {
+		t.Fatalf("mismatches = %v", mismatches)
+	}
+
+	c = New(10, WithTypeGuard(true, nil))
+	c.Set("user:1", "alice")
+	c.Set("user:1", "bob")
+	c.SetWithExpire2("user:1", 42, time.Minute, time.Hour)
+	if v, _ := c.Get("user:1"); v != "bob" {
+		t.Fatalf("Get = %v, the write should have been rejected", v)
+	}
+	if n := c.Stats().TypeMismatches; n != 1 {
+		t.Fatalf("Stats().TypeMismatches = %d", n)
+	}
+}
+
+func TestTypeGuardDeparted(t *testing.T) {
+	c := New(1, WithTypeGuard(true, nil))
+	c.Set("user:1", "alice")
+	c.Set("other", 1)
+	if c.Has("user:1") {
+		t.Fatal("user:1 wasn't evicted")
+	}
+	c.Set("user:1", 42)
+	if c.Has("user:1") {
+		t.Fatal("a write of another type was accepted once the key was evicted")
+	}
+	c.Set("user:1", "bob")
+	if v, _ := c.Get("user:1"); v != "bob" {
+		t.Fatalf("Get = %v, want bob", v)
+	}
+
+	c.SetWithExpire("session", "s", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	c.Get("session")
+	c.Set("session", 1)
+	if n := c.Stats().TypeMismatches; n != 2 {
+		t.Fatalf("Stats().TypeMismatches = %d, want 2", n)
+	}
+
+	c.Clear()
+	c.Set("user:1", 42)
+	if v, _ := c.Get("user:1"); v != 42 {
+		t.Fatalf("Get = %v after Clear, want 42", v)
+	}
+}
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+)
+
+// VerifyReport is the outcome of Verify.
+type VerifyReport struct {
+	// Sampled counts the entries checked against the backend.
+	Sampled int
+	// Mismatches lists the keys whose cached value differs from
+	// the backend's.
+	Mismatches []Key
+	// Failed counts the entries the backend failed to load.
+	Failed int
+}
+
+// MismatchRate returns the share of the sampled entries which differ
+// from the backend.
+func (r VerifyReport) MismatchRate() float64 {
+	if r.Sampled == 0 {
+		return 0
+	}
+	return float64(len(r.Mismatches)) / float64(r.Sampled)
+}
+
+// Verify compares a random sample of fraction of the entries, from 0
+// to 1, with the values loaded from the backend, e.g. as a safety check
+// after warming a cache from data of uncertain age. Values are compared
+// with equal, reflect.DeepEqual if it's nil. The cache isn't modified.
+// If ctx is done first, Verify returns the report so far and the
+// error of the context.
+func (c *Cache) Verify(ctx context.Context, fraction float64, loader LoaderFunc, equal func(a, b interface{}) bool) (VerifyReport, error) {
+	if equal == nil {
+		equal = reflect.DeepEqual
+	}
+	var sample []Entry
+	c.mu.Lock()
+	if c.store != nil {
+		now := c.now()
+		for ele := c.ll.Front(); ele != nil; ele = ele.Next() {
+			if e := ele.Value.(*entry); !e.expired(now) && rand.Float64() < fraction {
+				sample = append(sample, Entry{Key: e.key, Value: e.value})
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	var r VerifyReport
+	for _, e := range sample {
+		if err := ctx.Err(); err != nil {
+			return r, err
+		}
+		v, err := loader(e.Key)
+		r.Sampled++
+		switch {
+		case err != nil:
+			r.Failed++
+		case !equal(e.Value, v):
+			r.Mismatches = append(r.Mismatches, e.Key)
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	c := New(0)
+	backend := map[Key]int{}
+	for i := 0; i < 100; i++ {
+		c.Set(i, i)
+		backend[i] = i
+	}
+	backend[7] = -7
+	delete(backend, 8)
+	loader := func(key Key) (interface{}, error) {
+		v, ok := backend[key]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return v, nil
+	}
+
+	r, err := c.Verify(context.Background(), 1, loader, nil)
+	if err != nil || r.Sampled != 100 || r.Failed != 1 || len(r.Mismatches) != 1 || r.Mismatches[0] != 7 {
+		t.Fatalf("Verify = %+v, %v", r, err)
+	}
+	if rate := r.MismatchRate(); rate != 0.01 {
+		t.Fatalf("MismatchRate() = %v", rate)
+	}
+	if r, _ := c.Verify(context.Background(), 0, loader, nil); r.Sampled != 0 {
+		t.Fatalf("Verify sampled %d entries with a zero fraction", r.Sampled)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Verify(ctx, 1, loader, nil); err != context.Canceled {
+		t.Fatalf("Verify error = %v with a canceled context", err)
+	}
+}
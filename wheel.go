@@ -0,0 +1,178 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// wheelSize is the number of buckets of the expiration wheel.
+const wheelSize = 512
+
+// wheel is a hashed timing wheel of the entries with an expiration.
+// An entry expiring in tick t lives in bucket t%wheelSize, so inserting
+// and removing it is O(1) and expiring entries only needs to look at
+// the buckets of the ticks passed since the last advance.
+// Entries which expire more than one rotation ahead share a bucket
+// with closer ones and are skipped until their time comes.
+type wheel struct {
+	buckets []list.List
+	// tick is the resolution of the wheel in nanoseconds.
+	tick int64
+	// next is the first tick which hasn't been fully processed yet.
+	next int64
+}
+
+// defaultTick is the wheel resolution without a janitor.
+const defaultTick = int64(time.Second)
+
+// add schedules the expiration of e, which must have one.
+func (w *wheel) add(e *entry) {
+	if w.buckets == nil {
+		w.buckets = make([]list.List, wheelSize)
+		if w.tick <= 0 {
+			w.tick = defaultTick
+		}
+		w.next = nanotime() / w.tick
+	}
+	t := e.expire / w.tick
+	if t < w.next {
+		// The tick has been processed already, expire it on the next advance.
+		t = w.next
+	}
+	e.slot = int(t % wheelSize)
+	e.timer = w.buckets[e.slot].PushBack(e)
+}
+
+// remove unschedules the expiration of e.
+func (w *wheel) remove(e *entry) {
+	if e.timer == nil {
+		return
+	}
+	w.buckets[e.slot].Remove(e.timer)
+	e.timer = nil
+}
+
+// advance calls expire for every entry which expired by now.
+// expire may remove the entry from the wheel.
+func (w *wheel) advance(now int64, expire func(e *entry)) {
+	if w.buckets == nil {
+		return
+	}
+	cur := now / w.tick
+	from := w.next
+	if cur-from >= wheelSize {
+		from = cur - wheelSize + 1
+	}
+	for t := from; t <= cur; t++ {
+		b := &w.buckets[t%wheelSize]
+		for el := b.Front(); el != nil; {
+			next := el.Next()
+			if e := el.Value.(*entry); e.expire <= now {
+				expire(e)
+			}
+			el = next
+		}
+	}
+	// The current tick may hold entries expiring later within it.
+	w.next = cur
+}
+
+// reset drops every scheduled expiration.
+func (w *wheel) reset() {
+	w.buckets = nil
+}
+
+// WithJanitor removes expired entries every interval. Only the wheel buckets which came due are visited, so the
+// janitor's cost doesn't grow with the number of entries.
+// The wheel takes the resolution of interval, an interval of a few
+// milliseconds suits micro-caching with sub-second TTLs.
+// The janitor runs until Close is called.
+// It panics if interval isn't positive.
+func WithJanitor(interval time.Duration) Option {
+	if interval <= 0 {
+		panic("cache: WithJanitor interval must be positive")
+	}
+	return func(c *Cache) {
+		c.janitorInterval = interval
+		c.timers.tick = int64(interval)
+	}
+}
+
+func (c *Cache) startJanitor() {
+	c.janitor = c.every(c.janitorInterval, c.RemoveExpire)
+}
+
+// Close stops the background work of the cache.
+// The cache remains usable afterwards.
+func (c *Cache) Close() {
+	c.closeOnce.Do(func() {
+		if c.done != nil {
+			close(c.done)
+		}
+		c.periodicsMu.Lock()
+		for _, p := range c.periodics {
+			p.close()
+		}
+		c.periodics = nil
+		c.periodicsMu.Unlock()
+		if c.clock != nil {
+			atomic.StoreInt64(&c.clock.now, clockStopped)
+		}
+		if c.pool != nil {
+			c.pool.close()
+		}
+		if c.dispatcher != nil {
+			c.dispatcher.close()
+		}
+	})
+}
+
+// closer is embedded by Cache to stop its background work.
+type closer struct {
+	done      chan struct{}
+	closeOnce sync.Once
+	// periodics are the tasks started by every.
+	periodicsMu sync.Mutex
+	periodics   []*periodic
+}
+
+// scan calls fn for the entries in the buckets of the ticks up to
+// deadline, which includes every entry expiring by then.
+func (w *wheel) scan(deadline int64, fn func(e *entry)) {
+	if w.buckets == nil {
+		return
+	}
+	n := deadline/w.tick - w.next + 1
+	if n > wheelSize {
+		n = wheelSize
+	}
+	for t := w.next; t < w.next+n; t++ {
+		for el := w.buckets[t%wheelSize].Front(); el != nil; el = el.Next() {
+			fn(el.Value.(*entry))
+		}
+	}
+}
+
+// OnExpireSchedule schedules fn to be called in its own goroutine, or
+// on the pool set by WithWorkers, which may drop it, when the entry of key expires, even
+// if nobody looks it up again, e.g. for session timeout side effects.
+// fn fires when the janitor, see WithJanitor, or a lookup finds the
+// entry expired; it doesn't fire if the entry is removed or evicted
+// before expiring. The schedule
+// survives updates of the entry, which may move its expiration.
+// It reports false if key isn't in the cache or doesn't expire.
+func (c *Cache) OnExpireSchedule(key Key, fn func(key Key, value interface{})) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil {
+		return false
+	}
+	ele, ok := c.store.Get(key)
+	if !ok || ele.Value.(*entry).expire == 0 {
+		return false
+	}
+	ele.Value.(*entry).onExpire = fn
+	return true
+}
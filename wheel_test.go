@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWheelAdvance(t *testing.T) {
+	tick := int64(time.Millisecond)
+	w := wheel{tick: tick}
+	now := nanotime()
+	soon := &entry{key: "soon", expire: now + 2*tick}
+	late := &entry{key: "late", expire: now + (wheelSize+2)*tick}
+	gone := &entry{key: "gone", expire: now + 2*tick}
+	w.add(soon)
+	w.add(late)
+	w.add(gone)
+	w.remove(gone)
+
+	var expired []Key
+	collect := func(e *entry) {
+		expired = append(expired, e.key)
+		w.remove(e)
+	}
+	w.advance(now+tick, collect)
+	if len(expired) != 0 {
+		t.Fatalf("expired %v too early", expired)
+	}
+	w.advance(now+2*tick, collect)
+	if len(expired) != 1 || expired[0] != "soon" {
+		t.Fatalf("expired %v, want [soon]", expired)
+	}
+	// A stale entry is expired on the next advance.
+	past := &entry{key: "past", expire: now}
+	w.add(past)
+	w.advance(now+(wheelSize+2)*tick, collect)
+	if len(expired) != 3 {
+		t.Fatalf("expired %v, want [soon past late]", expired)
+	}
+}
+
+func TestJanitor(t *testing.T) {
+	ce := New(10, WithJanitor(time.Millisecond))
+	defer ce.Close()
+	ce.SetWithExpire("a", 1, 5*time.Millisecond)
+	ce.SetWithExpire("b", 2, time.Hour)
+	ce.Set("c", 3)
+	time.Sleep(50 * time.Millisecond)
+	if n := ce.ApproxLen(); n != 2 {
+		t.Fatalf("janitor left %d entries, want 2", n)
+	}
+}
+
+func TestJanitorZeroInterval(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("WithJanitor with a zero interval didn't panic")
+		}
+	}()
+	New(10, WithJanitor(0))
+}
+
+func TestMicroTTL(t *testing.T) {
+	ce := New(10)
+	ce.SetWithExpire("a", 1, 500*time.Microsecond)
+	if _, ok := ce.Get("a"); !ok {
+		t.Fatal("entry expired immediately")
+	}
+	time.Sleep(2 * time.Millisecond)
+	if _, ok := ce.Get("a"); ok {
+		t.Fatal("entry outlived its sub-millisecond TTL")
+	}
+}
+
+func TestOnExpireSchedule(t *testing.T) {
+	ce := New(10, WithJanitor(time.Millisecond))
+	defer ce.Close()
+	if ce.OnExpireSchedule("missing", nil) {
+		t.Fatal("scheduled a missing key")
+	}
+	ce.Set("forever", 1)
+	if ce.OnExpireSchedule("forever", nil) {
+		t.Fatal("scheduled a key which doesn't expire")
+	}
+
+	fired := make(chan Key, 1)
+	ce.SetWithExpire("session", 2, 5*time.Millisecond)
+	if !ce.OnExpireSchedule("session", func(key Key, _ interface{}) { fired <- key }) {
+		t.Fatal("OnExpireSchedule failed")
+	}
+	select {
+	case key := <-fired:
+		if key != "session" {
+			t.Fatalf("fired for %v", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the expiry callback didn't fire")
+	}
+}